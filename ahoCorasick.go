@@ -0,0 +1,94 @@
+package jsontology
+
+// ahoCorasick is a multi pattern matcher that finds whether any of its patterns occurs in a text
+// in a single pass over the text, regardless of the number of patterns.
+//
+// The automaton is stored as a deterministic transition table. To keep the table small, bytes that
+// do not occur in any pattern share a single column, i.e. the alphabet is reduced to byte classes.
+type ahoCorasick struct {
+	// classes maps each byte to its column in transitions
+	classes    [256]int32
+	numClasses int32
+	// transitions holds numClasses entries for every state, the root being state 0
+	transitions []int32
+	// match is true for the states in which a pattern ends
+	match []bool
+}
+
+// newAhoCorasick builds the automaton matching the given patterns.
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	a := &ahoCorasick{numClasses: 1}
+	for _, pattern := range patterns {
+		for i := 0; i < len(pattern); i++ {
+			if a.classes[pattern[i]] == 0 {
+				a.classes[pattern[i]] = a.numClasses
+				a.numClasses++
+			}
+		}
+	}
+
+	// build trie of all patterns, -1 marking a missing child
+	a.addState()
+	for _, pattern := range patterns {
+		var current int32
+		for i := 0; i < len(pattern); i++ {
+			index := current*a.numClasses + a.classes[pattern[i]]
+			if a.transitions[index] == -1 {
+				a.transitions[index] = a.addState()
+			}
+			current = a.transitions[index]
+		}
+		a.match[current] = true
+	}
+
+	// complete the transitions breadth first using fail links. The fail state of a state is the state of the longest
+	// proper suffix of its prefix that is also a prefix of a pattern, and is always complete before the state itself.
+	fail := make([]int32, len(a.match))
+	queue := []int32{}
+	for c := int32(0); c < a.numClasses; c++ {
+		if child := a.transitions[c]; child == -1 {
+			a.transitions[c] = 0
+		} else {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		a.match[current] = a.match[current] || a.match[fail[current]]
+		for c := int32(0); c < a.numClasses; c++ {
+			index := current*a.numClasses + c
+			fallback := a.transitions[fail[current]*a.numClasses+c]
+			if child := a.transitions[index]; child == -1 {
+				a.transitions[index] = fallback
+			} else {
+				fail[child] = fallback
+				queue = append(queue, child)
+			}
+		}
+	}
+	return a
+}
+
+func (a *ahoCorasick) addState() int32 {
+	for c := int32(0); c < a.numClasses; c++ {
+		a.transitions = append(a.transitions, -1)
+	}
+	a.match = append(a.match, false)
+	return int32(len(a.match) - 1)
+}
+
+// matchString reports whether any of the patterns occurs in text.
+func (a *ahoCorasick) matchString(text string) bool {
+	var current int32
+	if a.match[current] {
+		return true
+	}
+	for i := 0; i < len(text); i++ {
+		current = a.transitions[current*a.numClasses+a.classes[text[i]]]
+		if a.match[current] {
+			return true
+		}
+	}
+	return false
+}
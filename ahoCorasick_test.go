@@ -0,0 +1,89 @@
+package jsontology
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAhoCorasick(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		text     string
+		expected bool
+		name     string
+	}{
+		{[]string{"he", "she", "his", "hers"}, "ushers", true, "Classic Example"},
+		{[]string{"abcd", "bce"}, "abce", true, "Match Through Fail Link"},
+		{[]string{"abcd", "c"}, "abxc", true, "Suffix Pattern"},
+		{[]string{"abcd", "bcx"}, "abcx", true, "Fail To Longer Suffix"},
+		{[]string{"abc", "xyz"}, "abxyab", false, "No Match"},
+		{[]string{"error"}, "", false, "Empty Text"},
+		{[]string{}, "anything", false, "No Patterns"},
+		{[]string{""}, "anything", true, "Empty Pattern"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := newAhoCorasick(test.patterns).matchString(test.text)
+			if result != test.expected {
+				t.Errorf("matchString(%q) with patterns %v = %v; want %v", test.text, test.patterns, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestAhoCorasickMatchesContains(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomString := func(maxLength int) string {
+		b := make([]byte, random.Intn(maxLength)+1)
+		for i := range b {
+			b[i] = "abc"[random.Intn(3)]
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 500; i++ {
+		patterns := make([]string, random.Intn(5)+1)
+		for j := range patterns {
+			patterns[j] = randomString(4)
+		}
+		text := randomString(20)
+
+		expected := false
+		for _, pattern := range patterns {
+			expected = expected || strings.Contains(text, pattern)
+		}
+		if result := newAhoCorasick(patterns).matchString(text); result != expected {
+			t.Fatalf("matchString(%q) with patterns %v = %v; want %v", text, patterns, result, expected)
+		}
+	}
+}
+
+func BenchmarkContainsAnyOf(b *testing.B) {
+	keywords := make([]interface{}, 500)
+	for i := range keywords {
+		keywords[i] = fmt.Sprintf("keyword-%d;", i)
+	}
+	matcher, err := asKeywordMatcher(keywords)
+	if err != nil {
+		b.Fatal("unable to build keyword matcher, received error", err)
+	}
+	line := strings.Repeat("GET /index.html HTTP/1.1 200 user-agent=Mozilla ", 20) + "keyword-499;"
+
+	b.Run("ahoCorasick", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			isContainingAnyOf(matcher, line)
+		}
+	})
+	b.Run("stringsContains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, keyword := range keywords {
+				if strings.Contains(line, keyword.(string)) {
+					break
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package jsontology
+
+import (
+	"sort"
+	"strings"
+)
+
+// matcher evaluates a compiled constraint against a document.
+type matcher func(doc *document) bool
+
+// operatorCosts estimates the relative cost of evaluating an operator, operators missing from it having defaultOperatorCost.
+// Cheap checks, which are often also the most selective ones, are evaluated first so that expensive ones can be skipped.
+var operatorCosts = map[Operator]int{
+	exists:    1,
+	notExists: 1,
+	isNull:    1,
+	isType:    1,
+
+	equals:        2,
+	notEquals:     2,
+	greaterThan:   2,
+	lessThan:      2,
+	greaterEqual:  2,
+	lessEqual:     2,
+	between:       2,
+	in:            2,
+	notIn:         2,
+	length:        2,
+	lengthGreater: 2,
+	lengthLess:    2,
+
+	startsWith:    3,
+	endsWith:      3,
+	contains:      3,
+	notContains:   3,
+	containsAny:   3,
+	containsAll:   3,
+	equalsFold:    3,
+	startsFold:    3,
+	endsFold:      3,
+	containsFold:  3,
+	ipInRange:     3,
+	ipInAnyRange:  3,
+	isPrivateIP:   3,
+	isPublicIP:    3,
+	isLoopback:    3,
+	isLinkLocal:   3,
+	isMulticast:   3,
+	ipVersion:     3,
+	containsAnyOf: 4,
+	before:        4,
+	after:         4,
+	withinLast:    4,
+	dayOfWeek:     5,
+	hourBetween:   5,
+	regexMatch:    8,
+	notRegexMatch: 8,
+}
+
+const defaultOperatorCost = 5
+
+// compiledConstraint is a constraint bound to its matcher when the rule is created. It keeps the compiled conditions
+// of groups and of nested constraints, so that a match can be explained without compiling the constraint again.
+type compiledConstraint struct {
+	constraint constraint
+	match      matcher
+	// conditions are the compiled conditions of a group, or those applied to the elements of an array, ordered by cost
+	conditions []*compiledConstraint
+	// operatorFunc and typeHandler are those registered for the operator of criteria when the rule was created
+	operatorFunc OperatorFunc
+	typeHandler  OperatorTypeHandlerFunc
+}
+
+// compileConditions compiles the branches of a rule, i.e. a list of AND-ed constraints of which any has to match.
+func compileConditions(conditions [][]constraint) []*compiledConstraint {
+	branches := make([]*compiledConstraint, len(conditions))
+	for i, andConditions := range conditions {
+		branches[i] = compileAnd(andConditions)
+	}
+	return branches
+}
+
+// compileAnd compiles constraints which all have to match, evaluating them in order of their cost
+// and stopping at the first one that does not match.
+func compileAnd(conditions []constraint) *compiledConstraint {
+	compiled := &compiledConstraint{constraint: andGroup{conditions: conditions}, conditions: compileByCost(conditions)}
+	if len(compiled.conditions) == 1 {
+		compiled.match = compiled.conditions[0].match
+	} else {
+		compiled.match = func(doc *document) bool {
+			return matchAll(compiled.conditions, doc)
+		}
+	}
+	return compiled
+}
+
+// compileOr compiles constraints of which any has to match, evaluating them in order of their cost
+// and stopping at the first one that matches.
+func compileOr(conditions []constraint) *compiledConstraint {
+	compiled := &compiledConstraint{constraint: orGroup{conditions: conditions}, conditions: compileByCost(conditions)}
+	compiled.match = func(doc *document) bool {
+		return firstMatch(compiled.conditions, doc) >= 0
+	}
+	return compiled
+}
+
+// matchAll reports whether all of the compiled conditions match the document.
+func matchAll(conditions []*compiledConstraint, doc *document) bool {
+	for _, c := range conditions {
+		if !c.match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstMatch returns the position of the first of the compiled conditions matching the document, or -1 if none does.
+func firstMatch(conditions []*compiledConstraint, doc *document) int {
+	for i, c := range conditions {
+		if c.match(doc) {
+			return i
+		}
+	}
+	return -1
+}
+
+// compileByCost compiles constraints ordered from the cheapest to the most expensive.
+func compileByCost(conditions []constraint) []*compiledConstraint {
+	sorted := orderByCost(conditions)
+	compiled := make([]*compiledConstraint, len(sorted))
+	for i, c := range sorted {
+		compiled[i] = c.compile()
+	}
+	return compiled
+}
+
+// orderByCost returns a copy of conditions ordered from the cheapest to the most expensive constraint.
+// As constraints have no side effects, the order does not change the result of AND-ing or OR-ing them.
+func orderByCost(conditions []constraint) []constraint {
+	sorted := append([]constraint(nil), conditions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return cost(sorted[i]) < cost(sorted[j])
+	})
+	return sorted
+}
+
+// cost estimates the relative cost of evaluating a constraint, from the cost of its operator,
+// of resolving its fields and of evaluating any conditions it applies to the elements of an array.
+func cost(c constraint) int {
+	switch c := c.(type) {
+	case criteria:
+		total := operatorCost(c.operator) + resolverCost(c.resolver)
+		if c.reference != nil {
+			total += resolverCost(c.reference) + operatorCost(c.operator)
+		}
+		return total
+	case quantifiedCriteria:
+		return cost(c.criteria) + 1
+	case nestedCriteria:
+		return nestedCost(c)
+	case everyCriteria:
+		return nestedCost(nestedCriteria(c))
+	case noneCriteria:
+		return nestedCost(nestedCriteria(c))
+	case countCriteria:
+		return nestedCost(c.nestedCriteria)
+	case andGroup:
+		return sumCost(c.conditions)
+	case orGroup:
+		return sumCost(c.conditions)
+	case notGroup:
+		return cost(c.condition)
+	}
+	return defaultOperatorCost
+}
+
+func operatorCost(operator Operator) int {
+	if c, ok := operatorCosts[operator]; ok {
+		return c
+	}
+	return defaultOperatorCost
+}
+
+// resolverCost estimates the cost of resolving a field, top level fields being the cheapest.
+func resolverCost(resolver fieldResolver) int {
+	switch r := resolver.(type) {
+	case flatField:
+		if strings.Contains(string(r), ".") {
+			return 1
+		}
+		return 0
+	case *fieldPath:
+		total := 1
+		for _, selector := range r.selectors {
+			switch selector.(type) {
+			case descendantSelector, filterSelector:
+				total += 4
+			}
+		}
+		return total
+	}
+	return 1
+}
+
+// nestedCost estimates the cost of conditions applied to the elements of an array, which are evaluated for many elements.
+func nestedCost(n nestedCriteria) int {
+	return resolverCost(n.resolver) + 4*(1+sumCost(n.conditions))
+}
+
+func sumCost(conditions []constraint) int {
+	total := 0
+	for _, c := range conditions {
+		total += cost(c)
+	}
+	return total
+}
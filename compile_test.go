@@ -0,0 +1,78 @@
+package jsontology
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOrderByCost(t *testing.T) {
+	regex := criteria{field: "msg", resolver: flatField("msg"), operator: regexMatch, value: regexp.MustCompile("a+")}
+	nestedItems := nestedCriteria{path: "items", resolver: flatField("items"), conditions: []constraint{
+		criteria{field: "sku", resolver: flatField("sku"), operator: equals, value: "a"},
+	}}
+	equalsType := criteria{field: "type", resolver: flatField("type"), operator: equals, value: "login"}
+	equalsUser := criteria{field: "user.id", resolver: flatField("user.id"), operator: equals, value: 1}
+	existsHost := criteria{field: "host", resolver: flatField("host"), operator: exists, value: true}
+
+	ordered := orderByCost([]constraint{regex, nestedItems, equalsUser, equalsType, existsHost})
+	expected := []constraint{existsHost, equalsType, equalsUser, regex, nestedItems}
+	if !reflect.DeepEqual(ordered, expected) {
+		t.Errorf("orderByCost() = %v; want %v", ordered, expected)
+	}
+}
+
+func TestCompileShortCircuit(t *testing.T) {
+	var calls int
+	RegisterNewOperator("recordCall", func(ruleParam, eventParam interface{}) bool {
+		calls++
+		return true
+	}, nil)
+	t.Cleanup(func() { delete(operatorFuncMapping, "recordCall") })
+
+	table := []struct {
+		name      string
+		condition string
+		expected  bool
+		calls     int
+	}{
+		{name: "and stops at first mismatch", condition: `[{"a.$eq":2,"b.$recordCall":0}]`, expected: false, calls: 0},
+		{name: "and evaluates all on match", condition: `[{"a.$eq":1,"b.$recordCall":0}]`, expected: true, calls: 1},
+		{name: "or group stops at first match", condition: `[{"$or":[{"b.$recordCall":0},{"a.$eq":1}]}]`, expected: true, calls: 0},
+		{name: "branches stop at first match", condition: `[{"a.$eq":1},{"b.$recordCall":0}]`, expected: true, calls: 0},
+		{name: "branches continue after mismatch", condition: `[{"a.$eq":2},{"b.$recordCall":0}]`, expected: true, calls: 1},
+	}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewRule(strings.NewReader(test.condition), nil, nil)
+			if err != nil {
+				t.Fatal("unable to create rule, received error", err)
+			}
+			calls = 0
+			if result := r.IsMatch(map[string]interface{}{"a": 1, "b": 2}); result != test.expected || calls != test.calls {
+				t.Errorf("IsMatch() = %v with %d calls; want %v with %d calls", result, calls, test.expected, test.calls)
+			}
+		})
+	}
+}
+
+func TestCompileBindsOperator(t *testing.T) {
+	RegisterNewOperator("rebound", func(ruleParam, eventParam interface{}) bool { return true }, nil)
+	t.Cleanup(func() { delete(operatorFuncMapping, "rebound") })
+
+	r, err := NewRule(strings.NewReader(`[{"a.$rebound":0}]`), nil, nil)
+	if err != nil {
+		t.Fatal("unable to create rule, received error", err)
+	}
+	// registering the operator again only applies to rules created afterwards
+	RegisterNewOperator("rebound", func(ruleParam, eventParam interface{}) bool { return false }, nil)
+
+	data := map[string]interface{}{"a": 1}
+	if !r.IsMatch(data) {
+		t.Errorf("IsMatch() = false; want true")
+	}
+	if explanation := r.Explain(data); !explanation.Matched {
+		t.Errorf("Explain() matched = false; want true")
+	}
+}
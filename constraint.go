@@ -1,48 +1,298 @@
 package jsontology
 
+import "time"
+
 type constraint interface {
-	Evaluate(data map[string]interface{}) bool
+	// compile binds the constraint, and the conditions it applies, to the matchers evaluating them against a document
+	compile() *compiledConstraint
 }
 
-const (
-	keyNotFound string = "keyNotFound"
-)
+// Missing is passed to an OperatorFunc as the field value when the field is not present in the data.
+// Being a distinct type, it can never be confused with a real value found in the data.
+type Missing struct{}
+
+var keyNotFound = Missing{}
+
+// isMissing reports whether value is the Missing sentinel.
+func isMissing(value interface{}) bool {
+	_, ok := value.(Missing)
+	return ok
+}
 
 type criteria struct {
 	field    string
+	resolver fieldResolver
 	operator Operator
 	value    interface{}
+	// rawValue is the value as given in the rule, before it was passed through the type handler
+	rawValue interface{}
+	// reference resolves the rule value, if the value is taken from the data itself
+	reference fieldResolver
+	// normalizeString is applied to string field values before evaluation, if set.
+	normalizeString func(string) string
+	// clock returns the current time for time relative operators, if set, see WithClock.
+	clock func() time.Time
 }
 
 type nestedCriteria struct {
 	path       string
+	resolver   fieldResolver
+	conditions []constraint
+}
+
+// everyCriteria matches when every element of the array at path matches all of the conditions.
+type everyCriteria nestedCriteria
+
+// noneCriteria matches when no element of the array at path matches all of the conditions.
+type noneCriteria nestedCriteria
+
+// countCriteria counts the elements of the array at path matching all of the conditions,
+// and compares the count with value using a numeric operator.
+type countCriteria struct {
+	nestedCriteria
+	operator Operator
+	value    interface{}
+	rawValue interface{}
+}
+
+type quantifier string
+
+const (
+	anyElement  quantifier = "any"
+	allElements quantifier = "all"
+	noElement   quantifier = "none"
+)
+
+func isQuantifier(q quantifier) bool {
+	return q == anyElement || q == allElements || q == noElement
+}
+
+// quantifiedCriteria applies the operator of criteria to each element of an array field,
+// matching depending on the quantifier when any, all or none of the elements match.
+type quantifiedCriteria struct {
+	criteria
+	quantifier quantifier
+}
+
+// andGroup matches when all of its conditions match.
+type andGroup struct {
+	conditions []constraint
+}
+
+// orGroup matches when any of its conditions match.
+type orGroup struct {
 	conditions []constraint
 }
 
-func (c criteria) Evaluate(data map[string]interface{}) bool {
+// notGroup matches when its condition does not match.
+type notGroup struct {
+	condition constraint
+}
+
+func (c criteria) compile() *compiledConstraint {
+	operatorFunc, typeHandler := c.operatorFunc(), operatorTypeHandlerMapping[c.operator]
+	return &compiledConstraint{constraint: c, operatorFunc: operatorFunc, typeHandler: typeHandler, match: func(doc *document) bool {
+		ruleValue, ok := c.ruleValue(doc, typeHandler)
+		if !ok {
+			return false
+		}
+		value, ok := c.fieldValue(doc)
+		if !ok {
+			return operatorFunc(ruleValue, keyNotFound)
+		}
+		return operatorFunc(ruleValue, value)
+	}}
+}
+
+// operatorFunc returns the function of the operator, taking the current time from the clock of the rule if set.
+func (c criteria) operatorFunc() OperatorFunc {
+	if c.clock != nil && c.operator == withinLast {
+		return withinLastAt(c.clock)
+	}
+	return operatorFuncMapping[c.operator]
+}
 
-	if value, ok := data[c.field]; ok {
-		return operatorFuncMapping[c.operator](c.value, value)
+// ruleValue returns the value to compare the field with. For a reference to another field, the referenced value is
+// resolved from the document and passed through the type handler of the operator, returning false if it is absent or invalid.
+func (c criteria) ruleValue(doc *document, typeHandler OperatorTypeHandlerFunc) (interface{}, bool) {
+	if c.reference == nil {
+		return c.value, true
+	}
+	value, ok := c.reference.resolve(doc)
+	if !ok {
+		return nil, false
 	}
-	return operatorFuncMapping[c.operator](c.value, keyNotFound)
+	if c.normalizeString != nil {
+		value = normalizeStrings(value, c.normalizeString)
+	}
+	if typeHandler != nil {
+		transformedValue, err := typeHandler(value)
+		if err != nil {
+			return nil, false
+		}
+		value = transformedValue
+	}
+	return value, true
 }
 
-func (c nestedCriteria) Evaluate(data map[string]interface{}) bool {
+// fieldValue resolves the value of the field, normalized if the rule normalizes strings.
+func (c criteria) fieldValue(doc *document) (interface{}, bool) {
+	value, ok := c.resolver.resolve(doc)
+	if ok && c.normalizeString != nil {
+		value = normalizeStrings(value, c.normalizeString)
+	}
+	return value, ok
+}
 
-	// check if given path is array
-	arrayData, ok := data[c.path].([]interface{})
-	if ok {
+func (c nestedCriteria) compile() *compiledConstraint {
+	conditions := compileByCost(c.conditions)
+	return &compiledConstraint{constraint: c, conditions: conditions, match: func(doc *document) bool {
+		// the path has to be an array
+		arrayData, _ := c.arrayData(doc)
 		for _, eachData := range arrayData {
-			if formattedData, ok := eachData.(map[string]interface{}); ok {
-				andMatches := []bool{}
-				for _, e := range c.conditions {
-					andMatches = append(andMatches, e.Evaluate(formattedData))
-				}
-				if allMatch(andMatches) {
-					return true
-				}
+			if elementMatches(conditions, eachData) {
+				return true
 			}
 		}
+		return false
+	}}
+}
+
+// arrayData resolves the array at path.
+func (c nestedCriteria) arrayData(doc *document) ([]interface{}, bool) {
+	value, _ := c.resolver.resolve(doc)
+	arrayData, ok := value.([]interface{})
+	return arrayData, ok
+}
+
+// elementDocument returns the document of an array element, if it is an object the conditions of nested constraints apply to.
+func elementDocument(element interface{}) (*document, bool) {
+	formattedData, ok := element.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return newElementDocument(formattedData), true
+}
+
+// elementMatches checks whether an array element is an object matching all of the compiled conditions.
+func elementMatches(conditions []*compiledConstraint, element interface{}) bool {
+	doc, ok := elementDocument(element)
+	return ok && matchAll(conditions, doc)
+}
+
+func (c everyCriteria) compile() *compiledConstraint {
+	conditions := compileByCost(c.conditions)
+	return &compiledConstraint{constraint: c, conditions: conditions, match: func(doc *document) bool {
+		arrayData, ok := nestedCriteria(c).arrayData(doc)
+		if !ok {
+			return false
+		}
+		for _, eachData := range arrayData {
+			if !elementMatches(conditions, eachData) {
+				return false
+			}
+		}
+		return true
+	}}
+}
+
+func (c noneCriteria) compile() *compiledConstraint {
+	n := nestedCriteria(c).compile()
+	return &compiledConstraint{constraint: c, conditions: n.conditions, match: func(doc *document) bool {
+		return !n.match(doc)
+	}}
+}
+
+func (c countCriteria) compile() *compiledConstraint {
+	operatorFunc := operatorFuncMapping[c.operator]
+	conditions := compileByCost(c.conditions)
+	return &compiledConstraint{constraint: c, conditions: conditions, operatorFunc: operatorFunc, match: func(doc *document) bool {
+		count := 0
+		arrayData, _ := c.arrayData(doc)
+		for _, eachData := range arrayData {
+			// without conditions, every element is counted whether or not it is an object
+			if len(conditions) == 0 || elementMatches(conditions, eachData) {
+				count++
+			}
+		}
+		return operatorFunc(c.value, count)
+	}}
+}
+
+func (c quantifiedCriteria) compile() *compiledConstraint {
+	operatorFunc, typeHandler := c.operatorFunc(), operatorTypeHandlerMapping[c.operator]
+	return &compiledConstraint{constraint: c, operatorFunc: operatorFunc, typeHandler: typeHandler, match: func(doc *document) bool {
+		ruleValue, ok := c.ruleValue(doc, typeHandler)
+		if !ok {
+			return false
+		}
+		value, ok := c.fieldValue(doc)
+		if !ok {
+			return c.quantifier == noElement
+		}
+		for _, eachElement := range asArray(value) {
+			matches := operatorFunc(ruleValue, eachElement)
+			switch {
+			case matches && c.quantifier == anyElement:
+				return true
+			case matches && c.quantifier == noElement:
+				return false
+			case !matches && c.quantifier == allElements:
+				return false
+			}
+		}
+		return c.quantifier != anyElement
+	}}
+}
+
+func (g andGroup) compile() *compiledConstraint {
+	return compileAnd(g.conditions)
+}
+
+func (g orGroup) compile() *compiledConstraint {
+	return compileOr(g.conditions)
+}
+
+func (g notGroup) compile() *compiledConstraint {
+	condition := g.condition.compile()
+	return &compiledConstraint{constraint: g, conditions: []*compiledConstraint{condition}, match: func(doc *document) bool {
+		return !condition.match(doc)
+	}}
+}
+
+// flatFields calls fn for every field of the normalized data c looks up, excluding the fields of
+// nested conditions, which are looked up in array elements rather than the event itself.
+func flatFields(c constraint, fn func(string)) {
+	visit := func(resolver fieldResolver) {
+		if field, ok := resolver.(flatField); ok {
+			fn(string(field))
+		}
+	}
+	switch c := c.(type) {
+	case criteria:
+		visit(c.resolver)
+		if c.reference != nil {
+			visit(c.reference)
+		}
+	case quantifiedCriteria:
+		flatFields(c.criteria, fn)
+	case nestedCriteria:
+		visit(c.resolver)
+	case everyCriteria:
+		visit(c.resolver)
+	case noneCriteria:
+		visit(c.resolver)
+	case countCriteria:
+		visit(c.resolver)
+	case andGroup:
+		for _, each := range c.conditions {
+			flatFields(each, fn)
+		}
+	case orGroup:
+		for _, each := range c.conditions {
+			flatFields(each, fn)
+		}
+	case notGroup:
+		flatFields(c.condition, fn)
 	}
-	return false
 }
@@ -0,0 +1,144 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// document is the data constraints are evaluated against. It gives access to the original
+// structure of an event, used by path expressions, as well as to its normalized fields.
+//
+// Rather than flattening the whole event up front, the normalized value of a field is only resolved
+// when a constraint looks it up, and remembered if the same field is likely to be looked up again.
+type document struct {
+	raw map[string]interface{}
+	// normalize is false for the array elements evaluated by nested constraints, the fields of which are looked up as they are
+	normalize bool
+	// memoize is true if resolved fields are remembered, which only pays off if fields are looked up more than once
+	memoize bool
+	memo    map[string]resolvedField
+}
+
+type resolvedField struct {
+	value interface{}
+	found bool
+}
+
+// newDocument creates the document of an event.
+func newDocument(data map[string]interface{}, memoize bool) *document {
+	return &document{raw: data, normalize: true, memoize: memoize}
+}
+
+// newElementDocument creates the document of an array element evaluated by nested constraints.
+func newElementDocument(element map[string]interface{}) *document {
+	return &document{raw: element}
+}
+
+// field returns the value of field in the normalized data, i.e. the event merged with its flattened form, in which
+// the scalar values below each dotted path are collected under that path, across objects and arrays.
+func (d *document) field(field string) (interface{}, bool) {
+	if !d.normalize {
+		value, ok := d.raw[field]
+		return value, ok
+	}
+	if resolved, ok := d.memo[field]; ok {
+		return resolved.value, resolved.found
+	}
+
+	value, found := d.raw[field]
+	if strings.Contains(field, ".") {
+		if flatValue, ok := flatFieldValue(d.raw, field, 0); ok {
+			if found {
+				value = mergeFlatValues(copyArray(value), flatValue)
+			} else {
+				value, found = flatValue, true
+			}
+		}
+	}
+
+	if d.memoize {
+		if d.memo == nil {
+			d.memo = make(map[string]resolvedField)
+		}
+		d.memo[field] = resolvedField{value: value, found: found}
+	}
+	return value, found
+}
+
+// flatFieldValue returns the value the flattened form of node holds for key, the path of node being key[:consumed].
+// Instead of visiting the whole node, it only follows the keys key can be made of, which may themselves contain dots.
+func flatFieldValue(node interface{}, key string, consumed int) (interface{}, bool) {
+	switch v := node.(type) {
+	case nil, bool, int, float64, string, json.Number:
+		return v, consumed == len(key)
+
+	case map[string]interface{}:
+		if consumed == len(key) {
+			return nil, false
+		}
+		start := consumed
+		if consumed > 0 {
+			if key[consumed] != '.' {
+				return nil, false
+			}
+			start++
+		}
+		var result interface{}
+		found := false
+		for end := start; end <= len(key); end++ {
+			if end < len(key) && key[end] != '.' {
+				continue
+			}
+			if child, ok := v[key[start:end]]; ok {
+				if value, ok := flatFieldValue(child, key, end); ok {
+					result, found = mergeFlatValue(result, found, value), true
+				}
+			}
+		}
+		return result, found
+
+	case []interface{}:
+		var result interface{}
+		found := false
+		for _, element := range v {
+			if _, ok := element.(map[string]interface{}); ok {
+				if consumed < len(key) {
+					if value, ok := flatFieldValue(element, key, consumed); ok {
+						result, found = mergeFlatValue(result, found, value), true
+					}
+				}
+			} else if consumed == len(key) {
+				// scalars, and arrays, within an array are collected in an array of their own
+				elements, _ := result.([]interface{})
+				result, found = append(elements, element), true
+			}
+		}
+		return result, found
+	}
+	return nil, false
+}
+
+// mergeFlatValue merges value into the result of a flattened key, which is absent if found is false.
+func mergeFlatValue(result interface{}, found bool, value interface{}) interface{} {
+	if !found {
+		return value
+	}
+	return mergeFlatValues(result, value)
+}
+
+// mergeFlatValues merges two values of the same flattened key, appending value
+// to result if result is an array, and creating an array of both otherwise.
+func mergeFlatValues(result, value interface{}) interface{} {
+	if elements, ok := result.([]interface{}); ok {
+		return append(elements, value)
+	}
+	return []interface{}{result, value}
+}
+
+// copyArray copies value if it is an array, so that appending to it cannot modify the event.
+func copyArray(value interface{}) interface{} {
+	if elements, ok := value.([]interface{}); ok {
+		return append([]interface{}(nil), elements...)
+	}
+	return value
+}
@@ -0,0 +1,200 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocumentField(t *testing.T) {
+	events := []string{
+		`{"a": 1, "b": {"c": "d", "e": {"f": null}}}`,
+		`{"a": [{"b": 1}, {"b": 2}, {"c": 3}], "d": [1, 2, 3]}`,
+		`{"a": [{"b": [1, 2]}, {"b": [3]}, {"b": 4}]}`,
+		`{"a": {"b": [1, {"c": 2}, [3, 4]]}}`,
+		`{"a": [[{"b": 1}], {"b": 2}]}`,
+		`{"a.b": 1, "x": {"y.z": {"w": true}}}`,
+		`{"a.b": [1], "c": {"d": 2}}`,
+		`{"a": {"b": {}}, "c": {"d": []}}`,
+	}
+	for _, event := range events {
+		t.Run(event, func(t *testing.T) {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(event), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			normalized := normalize(data)
+			doc := newDocument(data, true)
+			for _, field := range append(keys(normalized), "a.x", "a.b.c.d", "x.y", "missing") {
+				expected, expectedFound := normalized[field]
+				value, found := doc.field(field)
+				if found != expectedFound || !reflect.DeepEqual(value, expected) {
+					t.Errorf("field(%s) = %v, %v; want %v, %v", field, value, found, expected, expectedFound)
+				}
+			}
+			if _, ok := doc.memo["missing"]; !ok {
+				t.Error("field(missing) was not memoized")
+			}
+		})
+	}
+
+	// resolving a field must not modify the arrays of the event
+	data := map[string]interface{}{"a.b": make([]interface{}, 1, 2), "a": map[string]interface{}{"b": 2}}
+	newDocument(data, false).field("a.b")
+	if elements := data["a.b"].([]interface{}); len(elements[:2]) != 2 || elements[:2][1] != nil {
+		t.Errorf("field(a.b) modified the event: %v", elements[:2])
+	}
+}
+
+func keys(m map[string]interface{}) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
+
+// largeEvent builds an event of about 50 KB with nested objects and arrays.
+func largeEvent() map[string]interface{} {
+	var event strings.Builder
+	event.WriteString(`{"user": {"id": 42, "name": "alice"}, "action": "login", "records": [`)
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			event.WriteString(",")
+		}
+		fmt.Fprintf(&event, `{"id": %d, "host": {"name": "host-%d", "ip": "10.0.%d.%d"}, "tags": ["a", "b", "c"], "metrics": {"cpu": %d, "mem": %d}}`,
+			i, i, i/256, i%256, i%100, i%64)
+	}
+	event.WriteString(`]}`)
+
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(event.String()), &data); err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func benchmarkRule(b *testing.B) *Rule {
+	r, err := NewRule(strings.NewReader(`[{"action.$eq": "login", "user.id.$eq": 42}]`), nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return r
+}
+
+func BenchmarkIsMatchLargeEvent(b *testing.B) {
+	r, data := benchmarkRule(b), largeEvent()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !r.IsMatch(data) {
+			b.Fatal("rule does not match")
+		}
+	}
+}
+
+// BenchmarkIsMatchLargeEventNormalized evaluates the same rule after flattening the whole event, as done before fields were resolved lazily.
+func BenchmarkIsMatchLargeEventNormalized(b *testing.B) {
+	r, data := benchmarkRule(b), largeEvent()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc := &document{raw: normalize(data)}
+		if !r.evaluate(doc) {
+			b.Fatal("rule does not match")
+		}
+	}
+}
+
+// normalize flattens data using transformJSON and merges the result with data itself,
+// so that both top level keys and dotted paths can be looked up in the returned map.
+// It is the reference document.field is tested against, documents resolving single fields the same way.
+func normalize(data map[string]interface{}) map[string]interface{} {
+	return concatMaps(data, transformJSON(data, ""))
+}
+
+// concatMaps merges two maps into a new map. If a key exists in both maps,
+// the value from the second map is appended to the value in the first map.
+// If the value in the first map is not a slice, it is converted to a slice
+// before appending the value from the second map.
+//
+// Parameters:
+// - m1: The first map to merge.
+// - m2: The second map to merge.
+//
+// Returns:
+// - A new map containing the merged key-value pairs.
+func concatMaps(m1 map[string]interface{}, m2 map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	for k, v := range m1 {
+		result[k] = v
+	}
+
+	for k, v := range m2 {
+
+		if value, ok := result[k]; ok {
+
+			switch vv := value.(type) {
+			case []interface{}:
+				result[k] = append(vv, v)
+			default:
+				result[k] = []interface{}{value, v}
+			}
+
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// transformJSON is a recursive function that transforms a nested JSON-like data structure into a flat map.
+// It handles nil, bool, int, float64, string, json.Number, map[string]interface{}, and []interface{} types.
+//
+// Parameters:
+// - data: The input data to be transformed. It can be of any type mentioned above.
+// - currentPath: The current path of the data in the nested structure. It is used to construct the keys in the flat map.
+//
+// Returns:
+// - A map[string]interface{} representing the flat map of the transformed data.
+//
+// Note:
+// - If the input data is a map[string]interface{}, it recursively calls itself for each value with the updated currentPath.
+// - If the input data is a []interface{}, it iterates over each value and handles it accordingly.
+// - If the input data is a nil, bool, int, float64, string or json.Number, it checks if the currentPath contains a dot and if it does, it adds the currentPath and the value to the result map.
+func transformJSON(data interface{}, currentPath string) map[string]interface{} {
+	var result = make(map[string]interface{})
+	switch v := data.(type) {
+	case nil, bool, int, float64, string, json.Number:
+		if strings.Contains(currentPath, ".") {
+			result[currentPath] = v
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if currentPath != "" {
+				key = currentPath + "." + key
+			}
+			result = concatMaps(result, transformJSON(value, key))
+		}
+	case []interface{}:
+		for _, eachValue := range v {
+			switch eachValue.(type) {
+			case map[string]interface{}:
+				result = concatMaps(result, transformJSON(eachValue, currentPath))
+			default:
+				if _, ok := result[currentPath]; ok {
+					d := result[currentPath].([]interface{})
+					result[currentPath] = append(d, eachValue)
+				} else {
+					if strings.Contains(currentPath, ".") {
+						result[currentPath] = []interface{}{eachValue}
+					}
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package jsontology
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ErrorPolicy decides what happens when handler fails to handle a match with err,
+// returning the error to pass on up the chain, or nil if the failure has been dealt with.
+type ErrorPolicy func(ctx context.Context, match *Match, handler EventHandler, err error) error
+
+// IgnoreErrors drops the errors of the handler.
+func IgnoreErrors() ErrorPolicy {
+	return func(ctx context.Context, match *Match, handler EventHandler, err error) error {
+		return nil
+	}
+}
+
+// LogErrors logs the errors of the handler to logger instead of returning them.
+func LogErrors(logger *log.Logger) ErrorPolicy {
+	return func(ctx context.Context, match *Match, handler EventHandler, err error) error {
+		logger.Printf("Event Handler Failed \n Rule : %s \n Error : %v", match.RuleID, err)
+		return nil
+	}
+}
+
+// RetryErrors calls the handler again, up to retries times and waiting delay before each retry,
+// returning the last error if the handler keeps failing or the context is done.
+func RetryErrors(retries int, delay time.Duration) ErrorPolicy {
+	return func(ctx context.Context, match *Match, handler EventHandler, err error) error {
+		for attempt := 0; attempt < retries && err != nil && ctx.Err() == nil; attempt++ {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(delay):
+			}
+			err = handle(ctx, handler, match)
+		}
+		return err
+	}
+}
+
+// FallbackOnError passes the matches the handler fails to handle on to fallback.
+func FallbackOnError(fallback EventHandler) ErrorPolicy {
+	return func(ctx context.Context, match *Match, handler EventHandler, err error) error {
+		return handle(ctx, fallback, match)
+	}
+}
+
+// ErrorPolicyEventHandler applies an ErrorPolicy to the errors of the handler it wraps, e.g. the sink at the end of a chain.
+// As a retried handler handles the match again, stateful handlers such as CountEventHandler should not be retried.
+type ErrorPolicyEventHandler struct {
+	handler EventHandler
+	policy  ErrorPolicy
+}
+
+func NewErrorPolicyEventHandler(handler EventHandler, policy ErrorPolicy) *ErrorPolicyEventHandler {
+	return &ErrorPolicyEventHandler{
+		handler: handler,
+		policy:  policy,
+	}
+}
+
+func (e *ErrorPolicyEventHandler) Handle(ctx context.Context, match *Match) error {
+	if err := handle(ctx, e.handler, match); err != nil {
+		return e.policy(ctx, match, e.handler, err)
+	}
+	return nil
+}
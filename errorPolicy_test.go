@@ -0,0 +1,111 @@
+package jsontology
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+var errSinkUnavailable = errors.New("sink unavailable")
+
+// failingHandler fails to handle the first failures matches.
+type failingHandler struct {
+	failures int
+	calls    int
+}
+
+func (h *failingHandler) Handle(ctx context.Context, match *Match) error {
+	h.calls++
+	if h.calls <= h.failures {
+		return errSinkUnavailable
+	}
+	return nil
+}
+
+func TestSendHandlerError(t *testing.T) {
+	sink := &failingHandler{failures: 1}
+	r, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, NewCountEventHandler(1, sink), WithID("r1"))
+
+	err := r.Send(strings.NewReader(`{"c": 1}`))
+	var handlerErr *HandlerError
+	if !errors.As(err, &handlerErr) || !errors.Is(err, errSinkUnavailable) {
+		t.Fatalf("Send() = %v; want a HandlerError wrapping %v", err, errSinkUnavailable)
+	}
+	if handlerErr.RuleID != "r1" || handlerErr.Handler != "failingHandler" {
+		t.Errorf("HandlerError = %+v; want rule r1 and handler failingHandler", handlerErr)
+	}
+
+	other, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, sink, WithID("r2"))
+	if err := NewRuleSet(r, other).Send(strings.NewReader(`{"c": 1}`)); err != nil {
+		t.Errorf("RuleSet.Send() = %v; want nil once the sink recovered", err)
+	}
+}
+
+func TestErrorPolicyEventHandler(t *testing.T) {
+	var logs bytes.Buffer
+	table := []struct {
+		name     string
+		failures int
+		policy   ErrorPolicy
+		calls    int
+		fallback int
+		err      bool
+	}{
+		{name: "ignore", failures: 1, policy: IgnoreErrors(), calls: 1},
+		{name: "log", failures: 1, policy: LogErrors(log.New(&logs, "", 0)), calls: 1},
+		{name: "retry until success", failures: 2, policy: RetryErrors(3, 0), calls: 3},
+		{name: "retry exhausted", failures: 5, policy: RetryErrors(3, 0), calls: 4, err: true},
+		{name: "fallback", failures: 1, calls: 1, fallback: 1},
+		{name: "no error", failures: 0, policy: IgnoreErrors(), calls: 1},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, fallback := &failingHandler{failures: tt.failures}, &failingHandler{}
+			if tt.policy == nil {
+				tt.policy = FallbackOnError(fallback)
+			}
+			r, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, NewErrorPolicyEventHandler(sink, tt.policy))
+			err := r.Send(strings.NewReader(`{"c": 1}`))
+			if (err != nil) != tt.err || sink.calls != tt.calls || fallback.calls != tt.fallback {
+				t.Errorf("Send() = %v with %d calls and %d fallback calls; want error %v with %d calls and %d fallback calls",
+					err, sink.calls, fallback.calls, tt.err, tt.calls, tt.fallback)
+			}
+		})
+	}
+	if !strings.Contains(logs.String(), errSinkUnavailable.Error()) {
+		t.Errorf("logged %q; want the handler error", logs.String())
+	}
+}
+
+func TestRetryErrorsContextDone(t *testing.T) {
+	sink := &failingHandler{failures: 5}
+	r, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, NewErrorPolicyEventHandler(sink, RetryErrors(3, 0)))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.SendContext(ctx, strings.NewReader(`{"c": 1}`)); !errors.Is(err, errSinkUnavailable) || sink.calls != 1 {
+		t.Errorf("SendContext() = %v with %d calls; want %v without retries", err, sink.calls, errSinkUnavailable)
+	}
+}
+
+func TestParseErrorPolicyEventHandler(t *testing.T) {
+	table := []struct {
+		name  string
+		chain string
+		err   bool
+	}{
+		{name: "retry", chain: `{"type":"ErrorPolicyEventHandler","params":{"policy":"retry","retries":3,"delay":100,"handler":{"type":"LogEventHandler","params":{}}}}`},
+		{name: "fallback", chain: `{"type":"ErrorPolicyEventHandler","params":{"policy":"fallback","fallback":{"type":"LogEventHandler","params":{}},"handler":{"type":"LogEventHandler","params":{}}}}`},
+		{name: "missing retries", chain: `{"type":"ErrorPolicyEventHandler","params":{"policy":"retry","handler":{"type":"LogEventHandler","params":{}}}}`, err: true},
+		{name: "unknown policy", chain: `{"type":"ErrorPolicyEventHandler","params":{"policy":"panic","handler":{"type":"LogEventHandler","params":{}}}}`, err: true},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GetEventHandlerChain(strings.NewReader(tt.chain)); (err != nil) != tt.err {
+				t.Errorf("GetEventHandlerChain() error = %v; want error %v", err, tt.err)
+			}
+		})
+	}
+}
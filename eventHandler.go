@@ -1,39 +1,102 @@
 package jsontology
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"reflect"
+	"sync"
 	"time"
 )
 
-type eventHandler interface {
-	call(eventJson, extraParams map[string]interface{})
+// EventHandler handles the matches of a rule. Handlers can be chained, each handler deciding
+// whether and when to pass a match on to the next one, e.g. after a number of matches.
+type EventHandler interface {
+	// Handle handles a match of a rule, returning an error if it could not be handled
+	Handle(ctx context.Context, match *Match) error
 }
 
+// HandlerFunc adapts a function to an EventHandler.
+type HandlerFunc func(ctx context.Context, match *Match) error
+
+// Handle calls f(ctx, match).
+func (f HandlerFunc) Handle(ctx context.Context, match *Match) error {
+	return f(ctx, match)
+}
+
+// HandlerError is the error returned by Send when an event handler fails to handle a match.
+type HandlerError struct {
+	RuleID string
+	// Handler is the type name of the failing handler of the chain, e.g. "LogEventHandler"
+	Handler string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler error, rule %s, %s: %v", e.RuleID, e.Handler, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// handle passes match on to handler. The error of the handler is wrapped in a HandlerError naming the handler,
+// unless it already names a handler further down the chain.
+func handle(ctx context.Context, handler EventHandler, match *Match) error {
+	err := handler.Handle(ctx, match)
+	if err == nil {
+		return nil
+	}
+	var handlerErr *HandlerError
+	if errors.As(err, &handlerErr) {
+		return err
+	}
+	return &HandlerError{RuleID: match.RuleID, Handler: handlerName(handler), Err: err}
+}
+
+func handlerName(handler EventHandler) string {
+	t := reflect.TypeOf(handler)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return t.String()
+	}
+	return t.Name()
+}
+
+// The built-in event handlers are safe for concurrent use, e.g. by a rule receiving events from several goroutines.
+// The chained handler is called without holding the lock of the handler, so it has to be safe for concurrent use itself.
+
 type LogEventHandler struct {
 	Logger *log.Logger
 }
 
 type CountEventHandler struct {
+	mu           sync.Mutex
 	currentCount int
 	count        int
-	handler      eventHandler
+	handler      EventHandler
 }
 
 type GroupByEventHandler struct {
+	mu           sync.Mutex
 	currentState map[interface{}]int
 	count        int
 	groupBy      string
-	handler      eventHandler
+	handler      EventHandler
 }
 
 type TimeBasedCountEventHandler struct {
+	mu           sync.Mutex
 	eventTimings []int
 	count        int
 	timeLimit    int
-	handler      eventHandler
+	handler      EventHandler
 }
 
-func NewCountEventHandler(count int, handler eventHandler) *CountEventHandler {
+func NewCountEventHandler(count int, handler EventHandler) *CountEventHandler {
 	return &CountEventHandler{
 		currentCount: 0,
 		count:        count,
@@ -41,7 +104,7 @@ func NewCountEventHandler(count int, handler eventHandler) *CountEventHandler {
 	}
 }
 
-func NewGroupByEventHandler(count int, groupBy string, handler eventHandler) *GroupByEventHandler {
+func NewGroupByEventHandler(count int, groupBy string, handler EventHandler) *GroupByEventHandler {
 	return &GroupByEventHandler{
 		currentState: make(map[interface{}]int),
 		count:        count,
@@ -49,50 +112,83 @@ func NewGroupByEventHandler(count int, groupBy string, handler eventHandler) *Gr
 		handler:      handler,
 	}
 }
-func NewTimeBasedCountEventHandler(count int, timeLimit int, handler eventHandler) *TimeBasedCountEventHandler {
+func NewTimeBasedCountEventHandler(count int, timeLimit int, handler EventHandler) *TimeBasedCountEventHandler {
 	return &TimeBasedCountEventHandler{
 		eventTimings: []int{},
 		count:        count,
 		timeLimit:    timeLimit,
+		handler:      handler,
 	}
 }
 
-func (l *LogEventHandler) call(eventJson, extraParams map[string]interface{}) {
-	l.Logger.Printf("Event Matched \n Event : %v \n Rule Params : %v", eventJson, extraParams)
+func (l *LogEventHandler) Handle(ctx context.Context, match *Match) error {
+	l.Logger.Printf("Event Matched \n Rule : %s \n Event : %v \n Rule Params : %v \n Matched Values : %v", match.RuleID, match.Event, match.Params, match.Values())
+	return nil
+}
+
+func (c *CountEventHandler) Handle(ctx context.Context, match *Match) error {
+	if c.countReached() {
+		return handle(ctx, c.handler, match)
+	}
+	return nil
 }
 
-func (c *CountEventHandler) call(eventJson, extraParams map[string]interface{}) {
+func (c *CountEventHandler) countReached() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.currentCount += 1
 	if c.currentCount == c.count {
-		c.handler.call(eventJson, extraParams)
 		// Reset the previous counter once handler is called
 		c.currentCount = 0
+		return true
+	}
+	return false
+}
+
+func (c *GroupByEventHandler) Handle(ctx context.Context, match *Match) error {
+	if c.countReached(match.Event[c.groupBy]) {
+		return handle(ctx, c.handler, match)
+	}
+	return nil
+}
+
+// countReached counts an event of the group, only the count of which can have been reached as the count
+// of every other group is reset once reached.
+func (c *GroupByEventHandler) countReached(group interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// numbers are grouped by value, e.g. 1 and 1.0 decoded as json.Number
+	if key, ok := setKey(group); ok {
+		group = key
 	}
+	c.currentState[group] += 1
+	if c.currentState[group] == c.count {
+		// Reset the previous counter once handler is called
+		delete(c.currentState, group)
+		return true
+	}
+	return false
 }
 
-func (c *GroupByEventHandler) call(eventJson, extraParams map[string]interface{}) {
-	c.currentState[eventJson[c.groupBy]] += 1
-	for key, value := range c.currentState {
-		if value == c.count {
-			c.handler.call(eventJson, extraParams)
-			// Reset the previous counter once handler is called
-			delete(c.currentState, key)
-		}
+func (c *TimeBasedCountEventHandler) Handle(ctx context.Context, match *Match) error {
+	if c.countReached(time.Now().Unix()) {
+		return handle(ctx, c.handler, match)
 	}
+	return nil
 }
 
-func (c *TimeBasedCountEventHandler) call(eventJson, extraParams map[string]interface{}) {
+func (c *TimeBasedCountEventHandler) countReached(currentTimestamp int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	// clean up expired timing
-	var currentTimestamp int64 = time.Now().Unix()
 	c.eventTimings = filter(c.eventTimings, func(x int) bool { return (x + c.timeLimit) >= int(currentTimestamp) })
 	// append new timings
 	c.eventTimings = append(c.eventTimings, int(currentTimestamp))
 
 	if len(c.eventTimings) == c.count {
-		c.handler.call(eventJson, extraParams)
-
 		// Reset the previous counter once handler is called
 		c.eventTimings = []int{}
+		return true
 	}
-
+	return false
 }
@@ -1,15 +1,15 @@
 package jsontology
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"time"
 )
 
 var eventHandlerParsingMap map[string]EventHandlerParsingFunctions
 
-type EventHandlerParsingFunctions func(params map[string]interface{}) (eventHandler, error)
+type EventHandlerParsingFunctions func(params map[string]interface{}) (EventHandler, error)
 
 func init() {
 	eventHandlerParsingMap = map[string]EventHandlerParsingFunctions{
@@ -17,10 +17,11 @@ func init() {
 		"GroupByEventHandler":        parseGroupByEventHandler,
 		"TimeBasedCountEventHandler": parseTimeBasedCountEventHandler,
 		"LogEventHandler":            parseLogEventHandler,
+		"ErrorPolicyEventHandler":    parseErrorPolicyEventHandler,
 	}
 }
 
-func buildEventHandlerChain(handlerChain map[string]interface{}) (eventHandler, error) {
+func buildEventHandlerChain(handlerChain map[string]interface{}) (EventHandler, error) {
 
 	if nestedHandlerData, hasNestedHandler := handlerChain["handler"]; hasNestedHandler {
 		nestedHandlerMap := nestedHandlerData.(map[string]interface{})
@@ -34,15 +35,11 @@ func buildEventHandlerChain(handlerChain map[string]interface{}) (eventHandler,
 	return eventHandlerParsingMap[handlerType](handlerParams)
 }
 
-func GetEventHandlerChain(handlerChain io.Reader) (eventHandler, error) {
+func GetEventHandlerChain(handlerChain io.Reader) (EventHandler, error) {
 
 	var parsedHandlerChain map[string]interface{}
 
-	handlerChainBytes, err := io.ReadAll(handlerChain)
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(handlerChainBytes, &parsedHandlerChain); err != nil {
+	if err := decodeJSON(handlerChain, &parsedHandlerChain); err != nil {
 		return nil, err
 	}
 	return buildEventHandlerChain(parsedHandlerChain)
@@ -53,11 +50,20 @@ func RegisterEventHandlerParser(handlerKey string, eventHandlerParsingFunc Event
 	eventHandlerParsingMap[handlerKey] = eventHandlerParsingFunc
 }
 
-func parseCountEventHandler(params map[string]interface{}) (eventHandler, error) {
+// intParam returns the integer value of a numeric param, numbers of a handler chain being decoded as json.Number.
+func intParam(params map[string]interface{}, name string) (int, bool) {
+	n, ok := asNumberValue(params[name])
+	if !ok {
+		return 0, false
+	}
+	return int(n.float()), true
+}
+
+func parseCountEventHandler(params map[string]interface{}) (EventHandler, error) {
 	// Validate "count" field
-	count, ok := params["count"].(float64)
+	count, ok := intParam(params, "count")
 	if !ok {
-		return nil, fmt.Errorf("invalid type for 'count': expected int, got %T", count)
+		return nil, fmt.Errorf("invalid type for 'count': expected int, got %T", params["count"])
 	}
 	// Validate "handler" field
 	handlerParams, ok := params["handler"].(map[string]interface{})
@@ -73,15 +79,15 @@ func parseCountEventHandler(params map[string]interface{}) (eventHandler, error)
 
 	return &CountEventHandler{
 		currentCount: 0,
-		count:        int(count),
+		count:        count,
 		handler:      resolvedHandler,
 	}, nil
 
 }
 
-func parseGroupByEventHandler(params map[string]interface{}) (eventHandler, error) {
+func parseGroupByEventHandler(params map[string]interface{}) (EventHandler, error) {
 	// Validate "count" field
-	count, ok := params["count"].(float64)
+	count, ok := intParam(params, "count")
 	if !ok {
 		return nil, fmt.Errorf("invalid or missing 'count': expected int, got %T", params["count"])
 	}
@@ -107,21 +113,21 @@ func parseGroupByEventHandler(params map[string]interface{}) (eventHandler, erro
 	return &GroupByEventHandler{
 		currentState: make(map[interface{}]int),
 		groupBy:      groupBy,
-		count:        int(count),
+		count:        count,
 		handler:      resolvedHandler,
 	}, nil
 
 }
 
-func parseTimeBasedCountEventHandler(params map[string]interface{}) (eventHandler, error) {
+func parseTimeBasedCountEventHandler(params map[string]interface{}) (EventHandler, error) {
 	// Validate "count" field
-	count, ok := params["count"].(float64)
+	count, ok := intParam(params, "count")
 	if !ok {
 		return nil, fmt.Errorf("invalid or missing 'count': expected int, got %T", params["count"])
 	}
 
 	// Validate "timeLimit" field
-	timeLimit, ok := params["timeLimit"].(int)
+	timeLimit, ok := intParam(params, "timeLimit")
 	if !ok {
 		return nil, fmt.Errorf("invalid or missing 'timeLimit': expected int, got %T", params["timeLimit"])
 	}
@@ -141,14 +147,62 @@ func parseTimeBasedCountEventHandler(params map[string]interface{}) (eventHandle
 	return &TimeBasedCountEventHandler{
 		eventTimings: []int{},
 		timeLimit:    timeLimit,
-		count:        int(count),
+		count:        count,
 		handler:      resolvedHandler,
 	}, nil
 
 }
 
-func parseLogEventHandler(params map[string]interface{}) (eventHandler, error) {
+func parseLogEventHandler(params map[string]interface{}) (EventHandler, error) {
 	return &LogEventHandler{
 		Logger: log.Default(),
 	}, nil
 }
+
+func parseErrorPolicyEventHandler(params map[string]interface{}) (EventHandler, error) {
+	// Validate "handler" field
+	handlerParams, ok := params["handler"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid or missing 'handler': expected map[string]interface{}, got %T", params["handler"])
+	}
+
+	// Resolve handler chain
+	resolvedHandler, err := buildEventHandlerChain(handlerParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve handler chain: %w", err)
+	}
+
+	// Validate "policy" field
+	var policy ErrorPolicy
+	switch params["policy"] {
+	case "ignore":
+		policy = IgnoreErrors()
+	case "log":
+		policy = LogErrors(log.Default())
+	case "retry":
+		retries, ok := intParam(params, "retries")
+		if !ok {
+			return nil, fmt.Errorf("invalid or missing 'retries': expected int, got %T", params["retries"])
+		}
+		// "delay" is optional, in milliseconds
+		delay, _ := intParam(params, "delay")
+		policy = RetryErrors(retries, time.Duration(delay)*time.Millisecond)
+	case "fallback":
+		fallbackParams, ok := params["fallback"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid or missing 'fallback': expected map[string]interface{}, got %T", params["fallback"])
+		}
+		fallback, err := buildEventHandlerChain(fallbackParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve fallback handler chain: %w", err)
+		}
+		policy = FallbackOnError(fallback)
+	default:
+		return nil, fmt.Errorf("invalid or missing 'policy': expected one of ignore, log, retry and fallback, got %v", params["policy"])
+	}
+
+	return &ErrorPolicyEventHandler{
+		handler: resolvedHandler,
+		policy:  policy,
+	}, nil
+}
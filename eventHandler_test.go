@@ -1,8 +1,14 @@
 package jsontology
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -12,8 +18,9 @@ type eventHandlerMock struct {
 	mock.Mock
 }
 
-func (m *eventHandlerMock) call(eventJson, extraParams map[string]interface{}) {
+func (m *eventHandlerMock) Handle(ctx context.Context, match *Match) error {
 	m.Called()
+	return nil
 }
 
 func TestCountEventHandler(t *testing.T) {
@@ -21,7 +28,7 @@ func TestCountEventHandler(t *testing.T) {
 		name       string
 		jsonEvents []string
 		condition  string
-		handler    eventHandler
+		handler    EventHandler
 		matches    int
 	}{
 		{ // Generate 1 alert if {"c":1} event is detected in stream 2 times
@@ -38,8 +45,8 @@ func TestCountEventHandler(t *testing.T) {
 	for _, tt := range table {
 		t.Run(tt.name, func(t *testing.T) {
 			h := tt.handler.(*CountEventHandler).handler.(*eventHandlerMock)
-			h.On("call").Times(tt.matches)
-			h.On("call").Return(nil)
+			h.On("Handle").Times(tt.matches)
+			h.On("Handle").Return(nil)
 			rule, _ := NewRule(strings.NewReader(tt.condition), map[string]interface{}{}, tt.handler)
 			for _, eachJson := range tt.jsonEvents {
 				rule.Send(strings.NewReader(eachJson))
@@ -54,7 +61,7 @@ func TestGroupByEventHandler(t *testing.T) {
 		name       string
 		jsonEvents []string
 		condition  string
-		handler    eventHandler
+		handler    EventHandler
 		matches    int
 	}{
 		{ // Generate 1 alert if same value for key [name] is in the stream for 2 times
@@ -75,8 +82,8 @@ func TestGroupByEventHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			h := tt.handler.(*GroupByEventHandler).handler.(*eventHandlerMock)
-			h.On("call").Times(tt.matches)
-			h.On("call").Return(nil)
+			h.On("Handle").Times(tt.matches)
+			h.On("Handle").Return(nil)
 
 			ruleCondition := []map[string]interface{}{}
 			err := json.Unmarshal([]byte(tt.condition), &ruleCondition)
@@ -115,3 +122,78 @@ func TestParseEventHandlerChain(t *testing.T) {
 	}
 
 }
+
+func TestHandlerFunc(t *testing.T) {
+	type ctxKey struct{}
+	handlerErr := errors.New("unable to deliver alert")
+	var received []interface{}
+	handler := HandlerFunc(func(ctx context.Context, match *Match) error {
+		received = append(received, ctx.Value(ctxKey{}), match.Event["c"])
+		return handlerErr
+	})
+
+	rule, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, NewCountEventHandler(2, handler))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	if err := rule.SendContext(ctx, strings.NewReader(`{"c": 1}`)); err != nil {
+		t.Fatal("unexpected error before count is reached", err)
+	}
+	if err := rule.SendContext(ctx, strings.NewReader(`{"c": 1}`)); !errors.Is(err, handlerErr) {
+		t.Errorf("SendContext() = %v; want %v", err, handlerErr)
+	}
+	if expected := []interface{}{"request", json.Number("1")}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("handler received %v; want %v", received, expected)
+	}
+}
+
+func TestEventHandlersConcurrentSend(t *testing.T) {
+	const goroutines, eventsPerGoroutine = 50, 100
+
+	table := []struct {
+		name    string
+		handler func(sink EventHandler) EventHandler
+		calls   int64
+	}{
+		{name: "count", handler: func(sink EventHandler) EventHandler { return NewCountEventHandler(5, sink) }, calls: 1000},
+		// every goroutine sends the events of its own group
+		{name: "group by", handler: func(sink EventHandler) EventHandler { return NewGroupByEventHandler(10, "g", sink) }, calls: 500},
+		{name: "time based count", handler: func(sink EventHandler) EventHandler { return NewTimeBasedCountEventHandler(4, 3600, sink) }, calls: 1250},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			sink := HandlerFunc(func(ctx context.Context, match *Match) error {
+				calls.Add(1)
+				return nil
+			})
+			rule, _ := NewRule(strings.NewReader(`[{"c.$eq":1}]`), nil, tt.handler(sink))
+
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < eventsPerGoroutine; i++ {
+						if err := rule.Send(strings.NewReader(fmt.Sprintf(`{"c": 1, "g": %d}`, g))); err != nil {
+							t.Error("unable to send event, received error", err)
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			if calls.Load() != tt.calls {
+				t.Errorf("handler called %d times; want %d", calls.Load(), tt.calls)
+			}
+		})
+	}
+}
+
+func TestParseTimeBasedCountEventHandler(t *testing.T) {
+	handler, err := GetEventHandlerChain(strings.NewReader(`{"type":"TimeBasedCountEventHandler","params":{"count":3,"timeLimit":60,"handler":{"type":"LogEventHandler","params":{}}}}`))
+	if err != nil {
+		t.Fatal("unable to parse event handlers, received error", err)
+	}
+	if h := handler.(*TimeBasedCountEventHandler); h.count != 3 || h.timeLimit != 60 || h.handler == nil {
+		t.Errorf("TimeBasedCountEventHandler = %+v; want count 3, timeLimit 60 and a handler", h)
+	}
+}
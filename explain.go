@@ -0,0 +1,213 @@
+package jsontology
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Explanation describes how the conditions of a rule were evaluated against an event, e.g. to find out
+// why a rule did or did not match. It can be serialized to JSON.
+type Explanation struct {
+	Matched bool `json:"matched"`
+	// Branches traces every object of the conditions list, any of which has to match for the rule to match
+	Branches []*Trace `json:"branches"`
+}
+
+// Trace describes the evaluation of a single condition.
+type Trace struct {
+	// Kind is one of "criteria", "nested", "every", "none", "count", "and", "or" and "not"
+	Kind string `json:"kind"`
+	// Field is the field the condition is applied to, as given in the rule
+	Field string `json:"field,omitempty"`
+	// Operator is the operator of the condition, including its quantifier, e.g. "all.eq"
+	Operator string `json:"operator,omitempty"`
+	// RuleValue is the value the field is compared with, as given in the rule or resolved from the referenced field
+	RuleValue interface{} `json:"ruleValue,omitempty"`
+	// Reference is the field the rule value is taken from, e.g. for {"$field": "dst.ip"}
+	Reference string `json:"reference,omitempty"`
+	// Value is the value of the field as found in the event, i.e. before any normalization,
+	// or for count the number of counted elements
+	Value interface{} `json:"value,omitempty"`
+	// Missing is true if the field, or the referenced field, is not present in the event
+	Missing bool `json:"missing,omitempty"`
+	Result  bool `json:"result"`
+	// Groups holds the capture groups of a matching rgx condition, the first one being the whole match
+	Groups []string `json:"groups,omitempty"`
+	// Conditions traces the conditions of a logical group
+	Conditions []*Trace `json:"conditions,omitempty"`
+	// Elements traces the conditions applied to each element of an array, e.g. by nested
+	Elements []*ElementTrace `json:"elements,omitempty"`
+}
+
+// ElementTrace describes the evaluation of the conditions applied to an element of an array.
+type ElementTrace struct {
+	Index int `json:"index"`
+	// Result is true if the element is an object matching all of the conditions
+	Result     bool     `json:"result"`
+	Conditions []*Trace `json:"conditions,omitempty"`
+}
+
+// Explain evaluates the rule's conditions against data like IsMatch, tracing the evaluation of every condition.
+// Unlike IsMatch, every condition is evaluated even if the result is already known.
+func (r *Rule) Explain(data map[string]interface{}) *Explanation {
+	return r.explain(newDocument(data, true))
+}
+
+func (r *Rule) explain(doc *document) *Explanation {
+	explanation := &Explanation{}
+	for _, branch := range r.branches {
+		trace := branch.explain(doc)
+		explanation.Branches = append(explanation.Branches, trace)
+		explanation.Matched = explanation.Matched || trace.Result
+	}
+	return explanation
+}
+
+// explain traces the evaluation of the compiled constraint against the document, its conditions being traced
+// in the order they are evaluated in.
+func (n *compiledConstraint) explain(doc *document) *Trace {
+	switch c := n.constraint.(type) {
+	case criteria:
+		return c.explainAs(string(c.operator), n, doc)
+	case quantifiedCriteria:
+		return c.criteria.explainAs(string(c.quantifier)+"."+string(c.operator), n, doc)
+	case nestedCriteria:
+		trace := explainElements("nested", c, n.conditions, doc)
+		for _, element := range trace.Elements {
+			trace.Result = trace.Result || element.Result
+		}
+		return trace
+	case everyCriteria:
+		trace := explainElements("every", nestedCriteria(c), n.conditions, doc)
+		_, trace.Result = nestedCriteria(c).arrayData(doc)
+		for _, element := range trace.Elements {
+			trace.Result = trace.Result && element.Result
+		}
+		return trace
+	case noneCriteria:
+		trace := explainElements("none", nestedCriteria(c), n.conditions, doc)
+		trace.Result = true
+		for _, element := range trace.Elements {
+			trace.Result = trace.Result && !element.Result
+		}
+		return trace
+	case countCriteria:
+		return explainCount(c, n, doc)
+	case andGroup:
+		return explainGroup("and", n.conditions, doc)
+	case orGroup:
+		return explainGroup("or", n.conditions, doc)
+	case notGroup:
+		conditionTrace := n.conditions[0].explain(doc)
+		return &Trace{Kind: "not", Result: !conditionTrace.Result, Conditions: []*Trace{conditionTrace}}
+	}
+	return &Trace{Result: n.match(doc)}
+}
+
+// explainGroup traces the compiled conditions of an AND or OR group.
+func explainGroup(kind string, conditions []*compiledConstraint, doc *document) *Trace {
+	trace := &Trace{Kind: kind, Result: kind == "and"}
+	for _, c := range conditions {
+		conditionTrace := c.explain(doc)
+		trace.Conditions = append(trace.Conditions, conditionTrace)
+		if kind == "and" {
+			trace.Result = trace.Result && conditionTrace.Result
+		} else {
+			trace.Result = trace.Result || conditionTrace.Result
+		}
+	}
+	return trace
+}
+
+// explainAs traces criteria, the result of which is given by its compiled form n.
+func (c criteria) explainAs(operator string, n *compiledConstraint, doc *document) *Trace {
+	trace := &Trace{Kind: "criteria", Field: c.field, Operator: operator, RuleValue: c.rawValue, Result: n.match(doc)}
+	if reference, ok := asFieldReference(c.rawValue); ok {
+		trace.Reference = reference
+		referenced, ok := c.reference.resolve(doc)
+		trace.RuleValue, trace.Missing = referenced, !ok
+	}
+	value, ok := c.resolver.resolve(doc)
+	trace.Value, trace.Missing = value, trace.Missing || !ok
+	if c.operator == regexMatch && trace.Result {
+		ruleValue, _ := c.ruleValue(doc, n.typeHandler)
+		trace.Groups = c.captureGroups(ruleValue, doc)
+	}
+	return trace
+}
+
+// captureGroups returns the capture groups of the first string of the field value, or of its elements, matching the
+// regular expression. As the expression is matched against the normalized strings, the groups are mapped back to
+// the characters of the value as found in the event, e.g. "ß" for a group "ss" of its case folded form.
+func (c criteria) captureGroups(regex interface{}, doc *document) []string {
+	compiled, ok := regex.(*regexp.Regexp)
+	if !ok {
+		return nil
+	}
+	value, _ := c.resolver.resolve(doc)
+	for _, eachValue := range asArray(value) {
+		str, ok := eachValue.(string)
+		if !ok {
+			continue
+		}
+		if c.normalizeString == nil {
+			if groups := compiled.FindStringSubmatch(str); groups != nil {
+				return groups
+			}
+			continue
+		}
+		normalized, normalizedBounds, bounds := normalizeWithOffsets(str, c.normalizeString)
+		if index := compiled.FindStringSubmatchIndex(normalized); index != nil {
+			groups := make([]string, len(index)/2)
+			for i := range groups {
+				if start, end := index[2*i], index[2*i+1]; start >= 0 {
+					// groups are extended to whole characters of str, the last boundary at or before start
+					// and the first one at or after end
+					groups[i] = str[bounds[sort.SearchInts(normalizedBounds, start+1)-1]:bounds[sort.SearchInts(normalizedBounds, end)]]
+				}
+			}
+			return groups
+		}
+	}
+	return nil
+}
+
+// explainCount traces the compiled conditions counted by c, n being its compiled form.
+func explainCount(c countCriteria, n *compiledConstraint, doc *document) *Trace {
+	var trace *Trace
+	count := 0
+	if len(n.conditions) == 0 {
+		// without conditions, every element is counted whether or not it is an object
+		trace = &Trace{Kind: "count", Field: c.path}
+		arrayData, _ := c.arrayData(doc)
+		_, found := c.resolver.resolve(doc)
+		count, trace.Missing = len(arrayData), !found
+	} else {
+		trace = explainElements("count", c.nestedCriteria, n.conditions, doc)
+		for _, element := range trace.Elements {
+			if element.Result {
+				count++
+			}
+		}
+	}
+	trace.Operator, trace.RuleValue = string(c.operator), c.rawValue
+	trace.Value, trace.Result = count, n.operatorFunc(c.value, count)
+	return trace
+}
+
+// explainElements traces the compiled conditions applied to every element of the array at the path of c.
+func explainElements(kind string, c nestedCriteria, conditions []*compiledConstraint, doc *document) *Trace {
+	trace := &Trace{Kind: kind, Field: c.path}
+	arrayData, _ := c.arrayData(doc)
+	_, found := c.resolver.resolve(doc)
+	trace.Missing = !found
+	for i, eachData := range arrayData {
+		element := &ElementTrace{Index: i}
+		if elementDoc, ok := elementDocument(eachData); ok {
+			elementTrace := explainGroup("and", conditions, elementDoc)
+			element.Result, element.Conditions = elementTrace.Result, elementTrace.Conditions
+		}
+		trace.Elements = append(trace.Elements, element)
+	}
+	return trace
+}
@@ -0,0 +1,92 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRuleExplain(t *testing.T) {
+	r, err := NewRule(strings.NewReader(`[
+		{"type.$eq": "login", "src.ip.$eq": {"$field": "dst.ip"}},
+		{"items.$nested": {"price.$gt": 100}, "$not": {"user.$in": ["root"]}}
+	]`), nil, nil)
+	if err != nil {
+		t.Fatal("unable to parse to rule, received error : ", err)
+	}
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(`{"type":"login","src":{"ip":"10.0.0.1"},"items":[{"price":50},{"price":150}],"user":"alice"}`), &data); err != nil {
+		t.Fatal("Invalid data", err)
+	}
+
+	explanation := r.Explain(data)
+	if !explanation.Matched || len(explanation.Branches) != 2 {
+		t.Fatalf("Explain() = %+v; want a match with 2 branches", explanation)
+	}
+
+	first := explanation.Branches[0]
+	if first.Result || len(first.Conditions) != 2 {
+		t.Fatalf("first branch = %+v; want a mismatch with 2 conditions", first)
+	}
+	for _, condition := range first.Conditions {
+		switch condition.Field {
+		case "type":
+			if !condition.Result || condition.RuleValue != "login" || condition.Value != "login" {
+				t.Errorf("type condition = %+v; want a match of login", condition)
+			}
+		case "src.ip":
+			if condition.Result || condition.Reference != "dst.ip" || !condition.Missing {
+				t.Errorf("src.ip condition = %+v; want a mismatch with missing dst.ip", condition)
+			}
+		default:
+			t.Errorf("unexpected condition %+v", condition)
+		}
+	}
+
+	second, _ := json.Marshal(explanation.Branches[1])
+	for _, expected := range []string{
+		`{"kind":"nested","field":"items","result":true,"elements":[`,
+		`{"index":0,"result":false,"conditions":[{"kind":"criteria","field":"price","operator":"gt","ruleValue":100,"value":50,"result":false}]}`,
+		`{"index":1,"result":true,"conditions":[{"kind":"criteria","field":"price","operator":"gt","ruleValue":100,"value":150,"result":true}]}`,
+		`{"kind":"not","result":true,"conditions":[{"kind":"and","result":false,"conditions":[{"kind":"criteria","field":"user","operator":"in","ruleValue":["root"],"value":"alice","result":false}]}]}`,
+	} {
+		if !strings.Contains(string(second), expected) {
+			t.Errorf("second branch = %s; want it to contain %s", second, expected)
+		}
+	}
+}
+
+func TestRuleExplainMatchesIsMatch(t *testing.T) {
+	conditions := []string{
+		`[{"tags.$all.eq":"prod"}]`,
+		`[{"tags.$none.eq":"dev"}]`,
+		`[{"items.$every":{"price.$gt":10}}]`,
+		`[{"items.$none":{"price.$gt":100}}]`,
+		`[{"items.$count":{"gte":2}}]`,
+		`[{"items.$count":{"where":{"price.$gt":10},"eq":1}}]`,
+		`[{"items.$nested":{}}]`,
+		`[{"$or":[{"a.$exists":true},{"b.$neq":1}]}]`,
+		`[{"$..price.$gt":100}]`,
+	}
+	events := []string{
+		`{"tags":["prod","prod"],"items":[{"price":20},{"price":5}],"b":1}`,
+		`{"tags":["dev"],"items":[{"price":150}],"a":null}`,
+		`{"items":[]}`,
+		`{}`,
+	}
+	for _, condition := range conditions {
+		r, err := NewRule(strings.NewReader(condition), nil, nil)
+		if err != nil {
+			t.Fatal("unable to parse to rule, received error : ", err)
+		}
+		for _, event := range events {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(event), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			if explained, matched := r.Explain(data).Matched, r.IsMatch(data); explained != matched {
+				t.Errorf("Explain(%s).Matched = %v for %s; want %v", event, explained, condition, matched)
+			}
+		}
+	}
+}
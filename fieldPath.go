@@ -0,0 +1,224 @@
+package jsontology
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fieldResolver looks up the value of a field in the document a constraint is evaluated against.
+type fieldResolver interface {
+	resolve(doc *document) (interface{}, bool)
+}
+
+// flatField looks up a field by its dotted name in the normalized data, in which the values
+// of every array along the way are merged, e.g. "a.b" resolving to all "b" of the objects in array "a".
+type flatField string
+
+func (f flatField) resolve(doc *document) (interface{}, bool) {
+	return doc.field(string(f))
+}
+
+// pathSelector selects the values of a single path segment from a node of the document.
+type pathSelector interface {
+	// selectFrom appends the values selected from node to selected, root being the root of the document.
+	selectFrom(root, node interface{}, selected []interface{}) []interface{}
+}
+
+// keySelector selects the value of a key of an object.
+type keySelector string
+
+// indexSelector selects an element of an array by its position, negative positions counting from the end.
+type indexSelector int
+
+// wildcardSelector selects all elements of an array or all values of an object.
+type wildcardSelector struct{}
+
+func (s keySelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	if object, ok := node.(map[string]interface{}); ok {
+		if value, ok := object[string(s)]; ok {
+			selected = append(selected, value)
+		}
+	}
+	return selected
+}
+
+func (s indexSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	if array, ok := node.([]interface{}); ok {
+		index := int(s)
+		if index < 0 {
+			index += len(array)
+		}
+		if index >= 0 && index < len(array) {
+			selected = append(selected, array[index])
+		}
+	}
+	return selected
+}
+
+func (s wildcardSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	switch node := node.(type) {
+	case []interface{}:
+		selected = append(selected, node...)
+	case map[string]interface{}:
+		for _, value := range node {
+			selected = append(selected, value)
+		}
+	}
+	return selected
+}
+
+// fieldPath resolves a path expression against the original structure of the document, rather than the normalized
+// data. It supports array positions, e.g. "a[0].b" or "a[-1]", wildcards, e.g. "a[*].b" or "a.*.status", and
+// quoted keys containing dots, e.g. `headers."x.forwarded.for"`.
+type fieldPath struct {
+	selectors []pathSelector
+	// multiple is true if the path can select more than one value, in which case the values are resolved as array
+	multiple bool
+}
+
+// pathSyntax are the characters making a field a path expression, unless they are escaped with a backslash.
+const pathSyntax = `[*"`
+
+// isPathSyntaxEscape reports whether field[i] is a backslash escaping the path syntax.
+func isPathSyntaxEscape(field string, i int) bool {
+	return field[i] == '\\' && i+1 < len(field) && strings.IndexByte(pathSyntax, field[i+1]) >= 0
+}
+
+// isPathExpression reports whether field uses the path syntax, rather than being a dotted name of the normalized data.
+func isPathExpression(field string) bool {
+	for i := 0; i < len(field); i++ {
+		if isPathSyntaxEscape(field, i) {
+			i++
+		} else if strings.IndexByte(pathSyntax, field[i]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeFieldName returns the dotted name of field, removing the backslashes escaping the path syntax,
+// e.g. `tags\[0]` names the key "tags[0]".
+func unescapeFieldName(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var name strings.Builder
+	for i := 0; i < len(field); i++ {
+		if isPathSyntaxEscape(field, i) {
+			i++
+		}
+		name.WriteByte(field[i])
+	}
+	return name.String()
+}
+
+// parseFieldPath parses a path expression into its selectors.
+func parseFieldPath(field string) (*fieldPath, error) {
+	p := &fieldPath{}
+	rest := field
+	for len(rest) > 0 {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("parsing error, unterminated [ in path %s", field)
+			}
+			if inner := rest[1:end]; inner == "*" {
+				p.selectors = append(p.selectors, wildcardSelector{})
+				p.multiple = true
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("parsing error, invalid array index %s in path %s", inner, field)
+				}
+				p.selectors = append(p.selectors, indexSelector(index))
+			}
+			rest = rest[end+1:]
+
+		case rest[0] == '"':
+			key, length, err := unquotePathKey(rest)
+			if err != nil {
+				return nil, fmt.Errorf("parsing error, %v in path %s", err, field)
+			}
+			p.selectors = append(p.selectors, keySelector(key))
+			rest = rest[length:]
+
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if key := rest[:end]; key == "*" {
+				p.selectors = append(p.selectors, wildcardSelector{})
+				p.multiple = true
+			} else if key == "" || strings.ContainsAny(key, `*"]`) {
+				return nil, fmt.Errorf("parsing error, invalid key %q in path %s", key, field)
+			} else {
+				p.selectors = append(p.selectors, keySelector(key))
+			}
+			rest = rest[end:]
+		}
+
+		// segments are separated by dots, except for array positions which directly follow their array
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("parsing error, path %s ends with a dot", field)
+			}
+		} else if rest != "" && rest[0] != '[' {
+			return nil, fmt.Errorf("parsing error, expected . or [ in path %s", field)
+		}
+	}
+	return p, nil
+}
+
+// unquotePathKey reads a quoted key at the start of s, in which \" and \\ are escaped quotes and backslashes.
+// It returns the key and the length of the quoted key in s.
+func unquotePathKey(s string) (string, int, error) {
+	var key strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated escape")
+			}
+			i++
+			key.WriteByte(s[i])
+		case '"':
+			return key.String(), i + 1, nil
+		default:
+			key.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
+
+// resolve applies the selectors to the original structure of the document. Paths that can select more than one value
+// resolve to an array of all selected values, as long as at least one value is selected.
+func (p *fieldPath) resolve(doc *document) (interface{}, bool) {
+	nodes := p.selectAll(doc.raw, doc.raw)
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	if p.multiple {
+		return nodes, true
+	}
+	return nodes[0], true
+}
+
+// selectAll applies the selectors starting at node and returns all selected values.
+func (p *fieldPath) selectAll(root, node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	for _, selector := range p.selectors {
+		var selected []interface{}
+		for _, node := range nodes {
+			selected = selector.selectFrom(root, node, selected)
+		}
+		if len(selected) == 0 {
+			return nil
+		}
+		nodes = selected
+	}
+	return nodes
+}
@@ -0,0 +1,65 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFieldPath(t *testing.T) {
+	data := make(map[string]interface{})
+	err := json.Unmarshal([]byte(`{
+		"a": [{"b": 1, "c": [10, 20]}, {"b": 2, "c": [30]}],
+		"services": {"web": {"status": "up"}, "db": {"status": "down"}},
+		"headers": {"x.forwarded.for": "1.2.3.4", "quote\"d": "q"}
+	}`), &data)
+	if err != nil {
+		t.Fatal("Invalid data", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{`a[0].b`, 1.0, true},
+		{`a[-1].b`, 2.0, true},
+		{`a[1].c[0]`, 30.0, true},
+		{`a[2].b`, nil, false},
+		{`a[*].b`, []interface{}{1.0, 2.0}, true},
+		{`a[*].c[-1]`, []interface{}{20.0, 30.0}, true},
+		{`a[*].d`, nil, false},
+		{`a.b[0]`, nil, false},
+		{`headers."x.forwarded.for"`, "1.2.3.4", true},
+		{`headers."quote\"d"`, "q", true},
+		{`services.web.*`, []interface{}{"up"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parseFieldPath(test.path)
+			if err != nil {
+				t.Fatal("unable to parse path, received error", err)
+			}
+			result, found := p.resolve(newDocument(data, false))
+			if found != test.found || !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("resolve() = %v, %v; want %v, %v", result, found, test.expected, test.found)
+			}
+		})
+	}
+
+	// wildcards over objects select values in no particular order
+	p, _ := parseFieldPath(`services.*.status`)
+	if result, _ := p.resolve(newDocument(data, false)); len(result.([]interface{})) != 2 {
+		t.Errorf("resolve() = %v; want both statuses", result)
+	}
+}
+
+func TestFieldPathParsingErrors(t *testing.T) {
+	for _, path := range []string{`a[0`, `a[x]`, `a.`, `a..b`, `"a`, `a"b"`, `a[0]b`, `a]`} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := parseFieldPath(path); err == nil {
+				t.Errorf("parseFieldPath(%s) error = nil, want parsing error", path)
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// isJSONPath reports whether field is a JSONPath expression, i.e. starts at the root $ of the document.
+func isJSONPath(field string) bool {
+	return field == "$" || strings.HasPrefix(field, "$.") || strings.HasPrefix(field, "$[")
+}
+
+// descendantSelector applies its selector to a node and all of its descendants, i.e. the recursive descent "..".
+type descendantSelector struct {
+	selector pathSelector
+}
+
+// sliceSelector selects the elements of an array from start up to, but excluding, end in steps of step.
+// A nil start or end selects from the first or up to the last element, negative positions count from the end.
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+// unionSelector selects the values of all of its selectors, e.g. ['a','b'] or [0,2].
+type unionSelector []pathSelector
+
+// filterSelector selects the elements of an array, or values of an object, matching a filter expression.
+type filterSelector struct {
+	filter filterExpr
+}
+
+func (s descendantSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	selected = s.selector.selectFrom(root, node, selected)
+	switch node := node.(type) {
+	case []interface{}:
+		for _, element := range node {
+			selected = s.selectFrom(root, element, selected)
+		}
+	case map[string]interface{}:
+		for _, value := range node {
+			selected = s.selectFrom(root, value, selected)
+		}
+	}
+	return selected
+}
+
+func (s sliceSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	array, ok := node.([]interface{})
+	if !ok || s.step == 0 {
+		return selected
+	}
+	bound := func(position *int, fallback int) int {
+		if position == nil {
+			return fallback
+		}
+		index := *position
+		if index < 0 {
+			index += len(array)
+		}
+		return min(max(index, -1), len(array))
+	}
+	if s.step > 0 {
+		for i := max(bound(s.start, 0), 0); i < bound(s.end, len(array)); i += s.step {
+			selected = append(selected, array[i])
+		}
+	} else {
+		for i := min(bound(s.start, len(array)-1), len(array)-1); i > bound(s.end, -1); i += s.step {
+			selected = append(selected, array[i])
+		}
+	}
+	return selected
+}
+
+func (s unionSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	for _, selector := range s {
+		selected = selector.selectFrom(root, node, selected)
+	}
+	return selected
+}
+
+func (s filterSelector) selectFrom(root, node interface{}, selected []interface{}) []interface{} {
+	switch node := node.(type) {
+	case []interface{}:
+		for _, element := range node {
+			if s.filter.matches(root, element) {
+				selected = append(selected, element)
+			}
+		}
+	case map[string]interface{}:
+		for _, value := range node {
+			if s.filter.matches(root, value) {
+				selected = append(selected, value)
+			}
+		}
+	}
+	return selected
+}
+
+// filterExpr is a boolean expression of a filter selector, evaluated against the current node @.
+type filterExpr interface {
+	matches(root, node interface{}) bool
+}
+
+type orFilter []filterExpr
+
+type andFilter []filterExpr
+
+type notFilter struct {
+	filter filterExpr
+}
+
+// existsFilter matches when its path selects at least one value, e.g. [?(@.isbn)].
+type existsFilter struct {
+	operand pathOperand
+}
+
+// comparisonFilter compares two operands, matching when any pair of their values compares as required.
+type comparisonFilter struct {
+	left, right filterOperand
+	comparator  string
+}
+
+// filterOperand is either a literal or a path relative to the current node @ or the root $.
+type filterOperand interface {
+	values(root, node interface{}) []interface{}
+}
+
+type literalOperand struct {
+	value interface{}
+}
+
+type pathOperand struct {
+	path *fieldPath
+	// relative is true for paths starting at the current node @
+	relative bool
+}
+
+func (f orFilter) matches(root, node interface{}) bool {
+	for _, filter := range f {
+		if filter.matches(root, node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f andFilter) matches(root, node interface{}) bool {
+	for _, filter := range f {
+		if !filter.matches(root, node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f notFilter) matches(root, node interface{}) bool {
+	return !f.filter.matches(root, node)
+}
+
+func (f existsFilter) matches(root, node interface{}) bool {
+	return len(f.operand.values(root, node)) > 0
+}
+
+func (f comparisonFilter) matches(root, node interface{}) bool {
+	for _, left := range f.left.values(root, node) {
+		for _, right := range f.right.values(root, node) {
+			if compareFilterValues(left, right, f.comparator) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o literalOperand) values(root, node interface{}) []interface{} {
+	return []interface{}{o.value}
+}
+
+func (o pathOperand) values(root, node interface{}) []interface{} {
+	if o.relative {
+		return o.path.selectAll(root, node)
+	}
+	return o.path.selectAll(root, root)
+}
+
+// compareFilterValues compares two values of a filter expression. Numbers are compared by value and strings
+// lexically, values of different types are never ordered, and only equal to each other if they are the same.
+func compareFilterValues(left, right interface{}, comparator string) bool {
+	if comparator == "==" || comparator == "!=" {
+		equal := false
+		if l, ok := asNumberValue(left); ok {
+			r, ok := asNumberValue(right)
+			c, comparable := l.compare(r)
+			equal = ok && comparable && c == 0
+		} else if isComparableFilterValue(left) && isComparableFilterValue(right) {
+			equal = left == right
+		}
+		return equal == (comparator == "==")
+	}
+
+	c, ok := 0, false
+	if l, isNumber := asNumberValue(left); isNumber {
+		if r, isNumber := asNumberValue(right); isNumber {
+			c, ok = l.compare(r)
+		}
+	} else if l, isString := left.(string); isString {
+		if r, isString := right.(string); isString {
+			c, ok = strings.Compare(l, r), true
+		}
+	}
+	if !ok {
+		return false
+	}
+	switch comparator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// isComparableFilterValue reports whether value can be compared with ==, arrays and objects, whatever
+// their Go type, cannot.
+func isComparableFilterValue(value interface{}) bool {
+	return value == nil || reflect.TypeOf(value).Comparable()
+}
+
+// jsonPathParser parses JSONPath expressions, e.g. "$..user.id" or "$.items[?(@.price > 10)].sku", supporting
+// recursive descent, wildcards, array positions, slices, unions of keys or positions and filter expressions.
+type jsonPathParser struct {
+	expr string
+	pos  int
+}
+
+// parseJSONPath parses a JSONPath expression into the selectors of a fieldPath.
+func parseJSONPath(expr string) (*fieldPath, error) {
+	p := &jsonPathParser{expr: expr}
+	path, err := p.parsePath('$')
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.expr) {
+		return nil, p.errorf("unexpected %q", p.expr[p.pos:])
+	}
+	return path, nil
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parsing error, %s at position %d of JSONPath %s", fmt.Sprintf(format, args...), p.pos, p.expr)
+}
+
+// parsePath parses a path starting with root, which is $ or, within filter expressions, @.
+func (p *jsonPathParser) parsePath(root byte) (*fieldPath, error) {
+	if !p.consume(string(root)) {
+		return nil, p.errorf("expected %c", root)
+	}
+	path := &fieldPath{}
+	for {
+		var selector pathSelector
+		var err error
+		switch {
+		case p.consume(".."):
+			if selector, err = p.parseSegment(); err != nil {
+				return nil, err
+			}
+			selector = descendantSelector{selector: selector}
+		case p.consume("."):
+			if selector, err = p.parseSegment(); err != nil {
+				return nil, err
+			}
+		case p.peek() == '[':
+			if selector, err = p.parseSegment(); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+		path.selectors = append(path.selectors, selector)
+		switch selector.(type) {
+		case keySelector, indexSelector:
+		default:
+			path.multiple = true
+		}
+	}
+}
+
+// parseSegment parses the selector following a dot, i.e. a wildcard, a key or, after "..", a bracketed selector.
+func (p *jsonPathParser) parseSegment() (pathSelector, error) {
+	switch {
+	case p.peek() == '[':
+		return p.parseBracket()
+	case p.consume("*"):
+		return wildcardSelector{}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && !strings.ContainsRune(".[]()=!<>&|,'\" *", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, p.errorf("expected key")
+	}
+	return keySelector(p.expr[start:p.pos]), nil
+}
+
+// parseBracket parses a bracketed selector, i.e. [*], a filter [?(...)] or a union of quoted keys, positions and slices.
+func (p *jsonPathParser) parseBracket() (pathSelector, error) {
+	p.consume("[")
+	p.skipSpaces()
+
+	var selector pathSelector
+	switch {
+	case p.consume("*"):
+		selector = wildcardSelector{}
+
+	case p.consume("?"):
+		p.skipSpaces()
+		filter, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+		selector = filterSelector{filter: filter}
+
+	default:
+		var union unionSelector
+		for {
+			p.skipSpaces()
+			var each pathSelector
+			var err error
+			if c := p.peek(); c == '\'' || c == '"' {
+				var key string
+				if key, err = p.parseString(); err == nil {
+					each = keySelector(key)
+				}
+			} else {
+				each, err = p.parseIndexOrSlice()
+			}
+			if err != nil {
+				return nil, err
+			}
+			union = append(union, each)
+			p.skipSpaces()
+			if !p.consume(",") {
+				break
+			}
+		}
+		selector = union
+		if len(union) == 1 {
+			selector = union[0]
+		}
+	}
+
+	p.skipSpaces()
+	if !p.consume("]") {
+		return nil, p.errorf("expected ]")
+	}
+	return selector, nil
+}
+
+// parseIndexOrSlice parses an array position, e.g. 2 or -1, or a slice, e.g. 1:3, :2 or ::-1.
+func (p *jsonPathParser) parseIndexOrSlice() (pathSelector, error) {
+	var positions [3]*int
+	part := 0
+	for {
+		p.skipSpaces()
+		start := p.pos
+		if p.peek() == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+			p.pos++
+		}
+		if start != p.pos {
+			position, err := strconv.Atoi(p.expr[start:p.pos])
+			if err != nil {
+				return nil, p.errorf("invalid array position %s", p.expr[start:p.pos])
+			}
+			positions[part] = &position
+		}
+		p.skipSpaces()
+		if part == 2 || !p.consume(":") {
+			break
+		}
+		part++
+	}
+
+	if part == 0 {
+		if positions[0] == nil {
+			return nil, p.errorf("expected array position")
+		}
+		return indexSelector(*positions[0]), nil
+	}
+	s := sliceSelector{start: positions[0], end: positions[1], step: 1}
+	if positions[2] != nil {
+		s.step = *positions[2]
+	}
+	if s.step == 0 {
+		return nil, p.errorf("slice step must not be 0")
+	}
+	return s, nil
+}
+
+// parseFilter parses a filter expression of || and && combined comparisons, with optional enclosing parentheses.
+func (p *jsonPathParser) parseFilter() (filterExpr, error) {
+	var or orFilter
+	for {
+		var and andFilter
+		for {
+			filter, err := p.parseUnaryFilter()
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, filter)
+			p.skipSpaces()
+			if !p.consume("&&") {
+				break
+			}
+		}
+		or = append(or, and)
+		if !p.consume("||") {
+			break
+		}
+	}
+	return or, nil
+}
+
+func (p *jsonPathParser) parseUnaryFilter() (filterExpr, error) {
+	p.skipSpaces()
+	if p.peek() == '!' && !strings.HasPrefix(p.expr[p.pos:], "!=") {
+		p.pos++
+		filter, err := p.parseUnaryFilter()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{filter: filter}, nil
+	}
+	if p.consume("(") {
+		filter, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return filter, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, comparator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(comparator) {
+			p.skipSpaces()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparisonFilter{left: left, right: right, comparator: comparator}, nil
+		}
+	}
+	path, ok := left.(pathOperand)
+	if !ok {
+		return nil, p.errorf("expected comparison")
+	}
+	return existsFilter{operand: path}, nil
+}
+
+// parseOperand parses a path starting with @ or $, or a literal string, number, boolean or null.
+func (p *jsonPathParser) parseOperand() (filterOperand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		path, err := p.parsePath(c)
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{path: path, relative: c == '@'}, nil
+	case c == '\'' || c == '"':
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literalOperand{value: value}, nil
+	}
+
+	for _, literal := range []struct {
+		token string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if p.consume(literal.token) {
+			return literalOperand{value: literal.value}, nil
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && strings.ContainsRune("+-.0123456789eE", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	value := json.Number(p.expr[start:p.pos])
+	if _, ok := parseNumber(value.String()); !ok {
+		return nil, p.errorf("expected path or literal")
+	}
+	return literalOperand{value: value}, nil
+}
+
+// parseString parses a string literal enclosed in single or double quotes, in which the quote and
+// backslash can be escaped with a backslash.
+func (p *jsonPathParser) parseString() (string, error) {
+	quote := p.expr[p.pos]
+	var value strings.Builder
+	for i := p.pos + 1; i < len(p.expr); i++ {
+		switch p.expr[i] {
+		case '\\':
+			if i+1 == len(p.expr) {
+				return "", p.errorf("unterminated escape")
+			}
+			i++
+			value.WriteByte(p.expr[i])
+		case quote:
+			p.pos = i + 1
+			return value.String(), nil
+		default:
+			value.WriteByte(p.expr[i])
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+// consume advances past token if the expression continues with it.
+func (p *jsonPathParser) consume(token string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipSpaces() {
+	for p.pos < len(p.expr) && p.expr[p.pos] == ' ' {
+		p.pos++
+	}
+}
@@ -0,0 +1,107 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	data := make(map[string]interface{})
+	err := json.Unmarshal([]byte(`{
+		"user": {"id": 1, "name": "alice"},
+		"items": [
+			{"sku": "a", "price": 5, "tags": ["new"]},
+			{"sku": "b", "price": 150, "user": {"id": 2}},
+			{"sku": "c", "price": 200, "discount": true}
+		],
+		"limit": 100,
+		"headers": {"x.forwarded.for": "1.2.3.4"}
+	}`), &data)
+	if err != nil {
+		t.Fatal("Invalid data", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{`$.user.id`, 1.0, true},
+		{`$['user']['name']`, "alice", true},
+		{`$.headers['x.forwarded.for']`, "1.2.3.4", true},
+		{`$.items[0].sku`, "a", true},
+		{`$.items[-1].sku`, "c", true},
+		{`$.items[5].sku`, nil, false},
+		{`$.items[*].sku`, []interface{}{"a", "b", "c"}, true},
+		{`$.items[0,2].sku`, []interface{}{"a", "c"}, true},
+		{`$.items[1:].sku`, []interface{}{"b", "c"}, true},
+		{`$.items[:-1].sku`, []interface{}{"a", "b"}, true},
+		{`$.items[::-1].sku`, []interface{}{"c", "b", "a"}, true},
+		{`$.items[?(@.price > 100)].sku`, []interface{}{"b", "c"}, true},
+		{`$.items[?(@.price > $.limit && !@.discount)].sku`, []interface{}{"b"}, true},
+		{`$.items[?(@.sku == 'a' || @.price >= 200)].sku`, []interface{}{"a", "c"}, true},
+		{`$.items[?(@.tags)].sku`, []interface{}{"a"}, true},
+		{`$.items[?@.user.id == 2].sku`, []interface{}{"b"}, true},
+		{`$.items[?(@.price < 1)].sku`, nil, false},
+		{`$.items[*].user..id`, []interface{}{2.0}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parseJSONPath(test.path)
+			if err != nil {
+				t.Fatal("unable to parse path, received error", err)
+			}
+			result, found := p.resolve(newDocument(data, false))
+			if found != test.found || !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("resolve() = %v, %v; want %v, %v", result, found, test.expected, test.found)
+			}
+		})
+	}
+
+	// recursive descent selects values in no particular order, and never sees the flattened keys of the normalized data
+	p, _ := parseJSONPath(`$..id`)
+	if result, _ := p.resolve(newDocument(data, false)); len(result.([]interface{})) != 2 {
+		t.Errorf("resolve() = %v; want both ids", result)
+	}
+}
+
+func TestJSONPathFilterTypedValues(t *testing.T) {
+	// data given to IsMatch may hold typed slices and maps, which can not be compared with ==
+	data := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"sku": "a", "tags": []string{"x"}, "labels": []string{"x"}},
+		map[string]interface{}{"sku": "b", "meta": map[string]string{"k": "v"}, "other": map[string]string{"k": "v"}},
+		map[string]interface{}{"sku": "c", "tags": []string{"x"}, "labels": "x"},
+	}}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{`$.items[?(@.tags == @.labels)].sku`, nil, false},
+		{`$.items[?(@.meta != @.other)].sku`, []interface{}{"b"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parseJSONPath(test.path)
+			if err != nil {
+				t.Fatal("unable to parse path, received error", err)
+			}
+			result, found := p.resolve(newDocument(data, false))
+			if found != test.found || !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("resolve() = %v, %v; want %v, %v", result, found, test.expected, test.found)
+			}
+		})
+	}
+}
+
+func TestJSONPathParsingErrors(t *testing.T) {
+	for _, path := range []string{`$.`, `$[`, `$[0`, `$['a`, `$[x]`, `$[::0]`, `$[?(@.a ==)]`, `$[?(@.a > 1]`, `$.a b`} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := parseJSONPath(path); err == nil {
+				t.Errorf("parseJSONPath(%s) error = nil, want parsing error", path)
+			}
+		})
+	}
+}
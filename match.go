@@ -0,0 +1,119 @@
+package jsontology
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Match describes a rule matching an event. It is passed down the event handler chain, so that
+// handlers know which rule matched and which values of the event satisfied its conditions.
+type Match struct {
+	// RuleID identifies the matched rule, see WithID
+	RuleID string
+	Event  map[string]interface{}
+	// Params are the params the rule was created with
+	Params map[string]interface{}
+	// Branch is the position of the first object of the conditions list matched by the event
+	Branch int
+
+	// describe returns the values of the event which satisfied the conditions of the matched branch
+	describe   func() []MatchedValue
+	valuesOnce sync.Once
+	values     []MatchedValue
+}
+
+// Values returns the values of the event which satisfied the conditions of the matched branch. Like the evaluation
+// of the branch, they stop at the first matching condition of $or and the first matching element of $nested.
+// They are only described when first requested, so that handlers not using them do not pay for it.
+func (m *Match) Values() []MatchedValue {
+	m.valuesOnce.Do(func() {
+		if m.describe != nil {
+			m.values = m.describe()
+		}
+	})
+	return m.values
+}
+
+// MatchedValue is a value of the event which satisfied a condition.
+type MatchedValue struct {
+	// Field is the field as given in the rule, prefixed with the array and position of the element for the
+	// conditions of nested, every and count, e.g. "items[1].price"
+	Field    string
+	Operator string
+	Value    interface{}
+	// Groups holds the capture groups of a matching rgx condition, the first one being the whole match
+	Groups []string
+}
+
+// newMatch describes how the given branch of the rule matched data. It is only called after the branch matched.
+func (r *Rule) newMatch(data map[string]interface{}, branch int) *Match {
+	return &Match{
+		RuleID: r.id,
+		Event:  data,
+		Params: r.extraParam,
+		Branch: branch,
+		describe: func() []MatchedValue {
+			// a document of its own, as the one data was evaluated with may be shared by the rules of a rule set
+			// and does not support concurrent lookups
+			return r.branches[branch].matchedValues(newDocument(data, false), "", nil)
+		},
+	}
+}
+
+// matchedValues appends the values which satisfied the compiled constraint, which matched the document, to values.
+// Like the matcher, it stops at the first matching condition of an OR group and at the first matching element of
+// nested. Conditions of not and none are skipped, as they are satisfied by the values which do not match.
+func (n *compiledConstraint) matchedValues(doc *document, prefix string, values []MatchedValue) []MatchedValue {
+	switch c := n.constraint.(type) {
+	case criteria:
+		return append(values, c.matchedValue(string(c.operator), n.typeHandler, doc, prefix))
+	case quantifiedCriteria:
+		return append(values, c.matchedValue(string(c.quantifier)+"."+string(c.operator), n.typeHandler, doc, prefix))
+	case andGroup:
+		for _, condition := range n.conditions {
+			values = condition.matchedValues(doc, prefix, values)
+		}
+	case orGroup:
+		if i := firstMatch(n.conditions, doc); i >= 0 {
+			values = n.conditions[i].matchedValues(doc, prefix, values)
+		}
+	case nestedCriteria:
+		values = matchedElementValues(c, n.conditions, doc, prefix, values, true)
+	case everyCriteria:
+		values = matchedElementValues(nestedCriteria(c), n.conditions, doc, prefix, values, false)
+	case countCriteria:
+		values = matchedElementValues(c.nestedCriteria, n.conditions, doc, prefix, values, false)
+	}
+	return values
+}
+
+// matchedValue describes the value of the field of matching criteria.
+func (c criteria) matchedValue(operator string, typeHandler OperatorTypeHandlerFunc, doc *document, prefix string) MatchedValue {
+	value, _ := c.resolver.resolve(doc)
+	matched := MatchedValue{Field: prefix + c.field, Operator: operator, Value: value}
+	if c.operator == regexMatch {
+		ruleValue, _ := c.ruleValue(doc, typeHandler)
+		matched.Groups = c.captureGroups(ruleValue, doc)
+	}
+	return matched
+}
+
+// matchedElementValues appends the values of the elements of the array at the path of c which matched the compiled
+// conditions, prefixing their fields with the array and position of the element, e.g. "items[1].price".
+func matchedElementValues(c nestedCriteria, conditions []*compiledConstraint, doc *document, prefix string, values []MatchedValue, firstOnly bool) []MatchedValue {
+	arrayData, _ := c.arrayData(doc)
+	for i, eachData := range arrayData {
+		elementDoc, ok := elementDocument(eachData)
+		if !ok || !matchAll(conditions, elementDoc) {
+			continue
+		}
+		elementPrefix := fmt.Sprintf("%s%s[%d].", prefix, c.path, i)
+		for _, condition := range conditions {
+			values = condition.matchedValues(elementDoc, elementPrefix, values)
+		}
+		if firstOnly {
+			break
+		}
+	}
+	return values
+}
@@ -0,0 +1,155 @@
+package jsontology
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingHandler struct {
+	matches []*Match
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, match *Match) error {
+	h.matches = append(h.matches, match)
+	return nil
+}
+
+func TestRuleSendMatch(t *testing.T) {
+	table := []struct {
+		name      string
+		condition string
+		params    map[string]interface{}
+		opts      []RuleOption
+		data      string
+		ruleID    string
+		branch    int
+		values    []MatchedValue
+	}{
+		{
+			name:      "second branch with regex groups",
+			condition: `[{"type.$eq":"logout"},{"type.$eq":"login","user.$rgx":"^(svc)-(\\w+)$"}]`,
+			opts:      []RuleOption{WithID("r1")},
+			data:      `{"type":"login","user":"svc-backup"}`,
+			ruleID:    "r1",
+			branch:    1,
+			values: []MatchedValue{
+				{Field: "type", Operator: "eq", Value: "login"},
+				{Field: "user", Operator: "rgx", Value: "svc-backup", Groups: []string{"svc-backup", "svc", "backup"}},
+			},
+		},
+		{
+			name:      "original values with case folding",
+			condition: `[{"user.$eq":"admin","street.$rgx":"^(straße) (\\d+)$"}]`,
+			opts:      []RuleOption{WithCaseFolding()},
+			data:      `{"user":"ADMIN","street":"Straße 12"}`,
+			values: []MatchedValue{
+				{Field: "user", Operator: "eq", Value: "ADMIN"},
+				{Field: "street", Operator: "rgx", Value: "Straße 12", Groups: []string{"Straße 12", "Straße", "12"}},
+			},
+		},
+		{
+			name:      "every element and counted elements",
+			condition: `[{"items.$every":{"price.$gt":10},"items.$count":{"where":{"price.$lt":100},"eq":1}}]`,
+			data:      `{"items":[{"price":50},{"price":150}]}`,
+			values: []MatchedValue{
+				{Field: "items[0].price", Operator: "lt", Value: json.Number("50")},
+				{Field: "items[0].price", Operator: "gt", Value: json.Number("50")},
+				{Field: "items[1].price", Operator: "gt", Value: json.Number("150")},
+			},
+		},
+		{
+			name:      "nested elements and or group",
+			condition: `[{"items.$nested":{"price.$gt":100},"$or":[{"a.$eq":1},{"b.$eq":2}],"$not":{"c.$eq":3}}]`,
+			params:    map[string]interface{}{"rule_id": 7},
+			data:      `{"items":[{"price":50},{"price":150},{"price":200}],"b":2}`,
+			ruleID:    "7",
+			branch:    0,
+			// conditions are reported in the order they are evaluated in, up to the first matching element of nested
+			values: []MatchedValue{
+				{Field: "b", Operator: "eq", Value: json.Number("2")},
+				{Field: "items[1].price", Operator: "gt", Value: json.Number("150")},
+			},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{}
+			r, err := NewRule(strings.NewReader(tt.condition), tt.params, NewCountEventHandler(1, handler), tt.opts...)
+			if err != nil {
+				t.Fatal("unable to parse to rule, received error : ", err)
+			}
+			if err := r.Send(strings.NewReader(tt.data)); err != nil {
+				t.Fatal("unable to send event, received error", err)
+			}
+			if len(handler.matches) != 1 {
+				t.Fatalf("handler called %d times; want 1", len(handler.matches))
+			}
+
+			match := handler.matches[0]
+			if match.RuleID != tt.ruleID || match.Branch != tt.branch || !reflect.DeepEqual(match.Params, tt.params) {
+				t.Errorf("Match = %+v; want rule %s, branch %d", match, tt.ruleID, tt.branch)
+			}
+			if values := match.Values(); !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("Match.Values() = %+v; want %+v", values, tt.values)
+			}
+		})
+	}
+}
+
+func TestRuleSetSendMatch(t *testing.T) {
+	handler := &recordingHandler{}
+	first, _ := NewRule(strings.NewReader(`[{"c.$eq":"1"}]`), nil, handler, WithID("first"))
+	second, _ := NewRule(strings.NewReader(`[{"c.$eq":"2"}]`), nil, handler, WithID("second"))
+
+	if err := NewRuleSet(first, second).Send(strings.NewReader(`{"c":"1"}`)); err != nil {
+		t.Fatal("unable to send event, received error", err)
+	}
+	if len(handler.matches) != 1 || handler.matches[0].RuleID != "first" || handler.matches[0].Event["c"] != "1" {
+		t.Errorf("matches = %+v; want a single match of rule first", handler.matches)
+	}
+}
+
+func BenchmarkRuleNewMatch(b *testing.B) {
+	r, err := NewRule(strings.NewReader(`[{"action.$eq":"login","records.$nested":{"id.$gte":0,"tags.$eq":"a"}}]`), nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := largeEvent()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc := newDocument(data, r.memoizeFields)
+		branch := r.matchingBranch(doc)
+		if branch < 0 {
+			b.Fatal("rule does not match")
+		}
+		r.newMatch(data, branch).Values()
+	}
+}
+
+func TestMatchValuesConcurrent(t *testing.T) {
+	handler := &recordingHandler{}
+	first, _ := NewRule(strings.NewReader(`[{"a.b.$eq":1,"c.$sw":"x"}]`), nil, handler, WithID("first"))
+	second, _ := NewRule(strings.NewReader(`[{"a.b.$eq":1,"c.$ew":"y"}]`), nil, handler, WithID("second"))
+	if err := NewRuleSet(first, second).Send(strings.NewReader(`{"a":{"b":1},"c":"xy"}`)); err != nil {
+		t.Fatal("unable to send event, received error", err)
+	}
+
+	// values are described on first use, possibly by handlers running concurrently
+	var wg sync.WaitGroup
+	for _, match := range append(handler.matches, handler.matches...) {
+		wg.Add(1)
+		go func(match *Match) {
+			defer wg.Done()
+			if values := match.Values(); len(values) != 2 {
+				t.Errorf("Match.Values() = %+v; want 2 values", values)
+			}
+		}(match)
+	}
+	wg.Wait()
+}
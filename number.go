@@ -0,0 +1,189 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+type numberKind int
+
+const (
+	signedNumber numberKind = iota
+	unsignedNumber
+	floatNumber
+)
+
+// number holds a numeric value of any Go kind in the representation that stores it exactly,
+// so that values of different kinds can be compared without losing precision.
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// asNumberValue converts any integer, unsigned integer or float kind as well as json.Number to a number.
+// Returns false if value is not numeric.
+func asNumberValue(value interface{}) (number, bool) {
+	if n, ok := value.(json.Number); ok {
+		return parseNumber(string(n))
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: signedNumber, i: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: unsignedNumber, u: v.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: floatNumber, f: v.Float()}, true
+	}
+	return number{}, false
+}
+
+// parseNumber parses the textual representation of a number, preferring integer representations
+// so that large integers keep their exact value.
+func parseNumber(s string) (number, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return number{kind: signedNumber, i: i}, true
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return number{kind: unsignedNumber, u: u}, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return number{kind: floatNumber, f: f}, true
+	}
+	return number{}, false
+}
+
+// float returns the number as float64, which might lose precision for large integers.
+func (n number) float() float64 {
+	switch n.kind {
+	case signedNumber:
+		return float64(n.i)
+	case unsignedNumber:
+		return float64(n.u)
+	}
+	return n.f
+}
+
+// compare returns -1, 0 or 1 if n is less than, equal to or greater than other.
+// Returns false if the numbers can not be ordered, i.e. one of them is NaN.
+func (n number) compare(other number) (int, bool) {
+	switch {
+	case n.kind == floatNumber && other.kind == floatNumber:
+		if math.IsNaN(n.f) || math.IsNaN(other.f) {
+			return 0, false
+		}
+		return compareOrdered(n.f, other.f), true
+
+	case n.kind == floatNumber:
+		c, ok := other.compare(n)
+		return -c, ok
+
+	case other.kind == floatNumber:
+		return n.compareFloat(other.f)
+
+	case n.kind == signedNumber && other.kind == signedNumber:
+		return compareOrdered(n.i, other.i), true
+
+	case n.kind == unsignedNumber && other.kind == unsignedNumber:
+		return compareOrdered(n.u, other.u), true
+
+	case n.kind == signedNumber:
+		if n.i < 0 {
+			return -1, true
+		}
+		return compareOrdered(uint64(n.i), other.u), true
+
+	default:
+		if other.i < 0 {
+			return 1, true
+		}
+		return compareOrdered(n.u, uint64(other.i)), true
+	}
+}
+
+// compareFloat compares an integer number with f exactly, without converting the integer to float64.
+func (n number) compareFloat(f float64) (int, bool) {
+	if math.IsNaN(f) {
+		return 0, false
+	}
+	truncated := math.Trunc(f)
+	var c int
+	if n.kind == signedNumber {
+		switch {
+		case truncated < math.MinInt64:
+			return 1, true
+		case truncated >= -math.MinInt64:
+			return -1, true
+		}
+		c = compareOrdered(n.i, int64(truncated))
+	} else {
+		switch {
+		case truncated < 0:
+			return 1, true
+		case truncated >= math.MaxUint64:
+			return -1, true
+		}
+		c = compareOrdered(n.u, uint64(truncated))
+	}
+	if c != 0 {
+		return c, true
+	}
+	// integer parts are equal, so only the fraction of f decides
+	return compareOrdered(truncated, f), true
+}
+
+// key returns a comparable value that is equal for all numbers that compare as equal.
+func (n number) key() interface{} {
+	switch n.kind {
+	case signedNumber:
+		return n.i
+	case unsignedNumber:
+		if n.u <= math.MaxInt64 {
+			return int64(n.u)
+		}
+		return n.u
+	}
+	if n.f == math.Trunc(n.f) {
+		switch {
+		case n.f >= math.MinInt64 && n.f < -math.MinInt64:
+			return int64(n.f)
+		case n.f >= 0 && n.f < math.MaxUint64:
+			return uint64(n.f)
+		}
+	}
+	return n.f
+}
+
+// compareNumeric converts both parameters to numbers and checks the result of their comparison with matches.
+// If eventParam is an array, it returns true if any of its elements satisfies the comparison.
+func compareNumeric(ruleParam, eventParam interface{}, matches func(c int) bool) bool {
+	if ruleNumber, ok := asNumberValue(ruleParam); ok {
+		if eventNumber, ok := asNumberValue(eventParam); ok {
+			c, ok := eventNumber.compare(ruleNumber)
+			return ok && matches(c)
+		}
+	}
+	switch eventParam := eventParam.(type) {
+	case []interface{}:
+		for _, eachElement := range eventParam {
+			if compareNumeric(ruleParam, eachElement, matches) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -1,10 +1,14 @@
 package jsontology
 
 import (
-	"net"
+	"encoding/json"
+	"math"
+	"net/netip"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Operator string
@@ -21,7 +25,51 @@ const (
 	regexMatch    Operator = "rgx"
 	notRegexMatch Operator = "nrgx"
 	nested        Operator = "nested"
+	every         Operator = "every"
+	none          Operator = "none"
+	count         Operator = "count"
 	ipInRange     Operator = "ipInRange"
+	exists        Operator = "exists"
+	notExists     Operator = "notExists"
+	isNull        Operator = "isNull"
+	isType        Operator = "isType"
+	in            Operator = "in"
+	notIn         Operator = "nin"
+	containsAny   Operator = "containsAny"
+	containsAll   Operator = "containsAll"
+	greaterEqual  Operator = "gte"
+	lessEqual     Operator = "lte"
+	between       Operator = "between"
+	equalsFold    Operator = "ieq"
+	startsFold    Operator = "isw"
+	endsFold      Operator = "iew"
+	containsFold  Operator = "icontains"
+	contains      Operator = "contains"
+	notContains   Operator = "ncontains"
+	containsAnyOf Operator = "containsAnyOf"
+	before        Operator = "before"
+	after         Operator = "after"
+	withinLast    Operator = "withinLast"
+	dayOfWeek     Operator = "dayOfWeek"
+	hourBetween   Operator = "hourBetween"
+	ipInAnyRange  Operator = "ipInAnyRange"
+	isPrivateIP   Operator = "isPrivateIP"
+	isPublicIP    Operator = "isPublicIP"
+	isLoopback    Operator = "isLoopback"
+	isLinkLocal   Operator = "isLinkLocal"
+	isMulticast   Operator = "isMulticast"
+	ipVersion     Operator = "ipVersion"
+	length        Operator = "len"
+	lengthGreater Operator = "lenGt"
+	lengthLess    Operator = "lenLt"
+)
+
+const (
+	stringType string = "string"
+	numberType string = "number"
+	boolType   string = "bool"
+	arrayType  string = "array"
+	objectType string = "object"
 )
 
 var operatorFuncMapping map[Operator]OperatorFunc = map[Operator]OperatorFunc{
@@ -34,6 +82,39 @@ var operatorFuncMapping map[Operator]OperatorFunc = map[Operator]OperatorFunc{
 	regexMatch:    isRegexMatch,
 	notRegexMatch: isNotRegexMatch,
 	ipInRange:     isIPInRange,
+	exists:        isExisting,
+	notExists:     isNotExisting,
+	isNull:        isNullValue,
+	isType:        isOfType,
+	in:            isInSet,
+	notIn:         isNotInSet,
+	containsAny:   isContainingAny,
+	containsAll:   isContainingAll,
+	greaterEqual:  isGreaterThanOrEqual,
+	lessEqual:     isLessThanOrEqual,
+	between:       isBetween,
+	equalsFold:    isEqualFold,
+	startsFold:    isStartingWithFold,
+	endsFold:      isEndingWithFold,
+	containsFold:  isContainingFold,
+	contains:      isContaining,
+	notContains:   isNotContaining,
+	containsAnyOf: isContainingAnyOf,
+	before:        isBefore,
+	after:         isAfter,
+	withinLast:    isWithinLast,
+	dayOfWeek:     isOnDayOfWeek,
+	hourBetween:   isHourBetween,
+	ipInAnyRange:  isIPInAnyRange,
+	isPrivateIP:   isIPOfClass(netip.Addr.IsPrivate),
+	isPublicIP:    isIPOfClass(isPublicAddr),
+	isLoopback:    isIPOfClass(netip.Addr.IsLoopback),
+	isLinkLocal:   isIPOfClass(netip.Addr.IsLinkLocalUnicast),
+	isMulticast:   isIPOfClass(netip.Addr.IsMulticast),
+	ipVersion:     isIPOfVersion,
+	length:        isLengthEqual,
+	lengthGreater: isLengthGreaterThan,
+	lengthLess:    isLengthLessThan,
 }
 
 var operatorTypeHandlerMapping map[Operator]OperatorTypeHandlerFunc = map[Operator]OperatorTypeHandlerFunc{
@@ -42,6 +123,57 @@ var operatorTypeHandlerMapping map[Operator]OperatorTypeHandlerFunc = map[Operat
 	regexMatch:    asRegexExpression,
 	notRegexMatch: asRegexExpression,
 	ipInRange:     asIpNet,
+	exists:        isBool,
+	notExists:     isBool,
+	isNull:        isBool,
+	isType:        asJsonType,
+	in:            asValueSet,
+	notIn:         asValueSet,
+	containsAny:   asValueSet,
+	containsAll:   asValueSet,
+	greaterEqual:  isNumber,
+	lessEqual:     isNumber,
+	between:       asNumberRange,
+	equalsFold:    asFoldedString,
+	startsFold:    asFoldedString,
+	endsFold:      asFoldedString,
+	containsFold:  asFoldedString,
+	contains:      isString,
+	notContains:   isString,
+	containsAnyOf: asKeywordMatcher,
+	before:        asTime,
+	after:         asTime,
+	withinLast:    asDuration,
+	dayOfWeek:     asWeekdays,
+	hourBetween:   asHourRange,
+	ipInAnyRange:  asPrefixTrie,
+	isPrivateIP:   isBool,
+	isPublicIP:    isBool,
+	isLoopback:    isBool,
+	isLinkLocal:   isBool,
+	isMulticast:   isBool,
+	ipVersion:     asIPVersion,
+	length:        isNumber,
+	lengthGreater: isNumber,
+	lengthLess:    isNumber,
+}
+
+// normalizedOperators are the operators comparing strings, which are normalized when a rule
+// is created using WithUnicodeNormalization or WithCaseFolding.
+var normalizedOperators = map[Operator]bool{
+	equals:        true,
+	notEquals:     true,
+	startsWith:    true,
+	endsWith:      true,
+	regexMatch:    true,
+	notRegexMatch: true,
+	in:            true,
+	notIn:         true,
+	containsAny:   true,
+	containsAll:   true,
+	contains:      true,
+	notContains:   true,
+	containsAnyOf: true,
 }
 
 // RegisterNewOperator registers a new operator with the system.
@@ -58,23 +190,21 @@ func RegisterNewOperator(op Operator, opFunc OperatorFunc, opTypeHandler Operato
 
 func isEquals(ruleParam, eventParam interface{}) bool {
 
+	ruleNumber, ruleIsNumber := asNumberValue(ruleParam)
+	eventNumber, eventIsNumber := asNumberValue(eventParam)
+	if ruleIsNumber && eventIsNumber {
+		c, ok := eventNumber.compare(ruleNumber)
+		return ok && c == 0
+	}
+
 	v1 := reflect.ValueOf(ruleParam)
 	v2 := reflect.ValueOf(eventParam)
-	if v1.Kind() == v2.Kind() {
+	if v1.Kind() == v2.Kind() && ruleIsNumber == eventIsNumber {
 		switch v1.Kind() {
 
 		case reflect.Bool:
 			return v1.Bool() == v2.Bool()
 
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return v1.Int() == v2.Int()
-
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			return v1.Uint() == v2.Uint()
-
-		case reflect.Float32, reflect.Float64:
-			return v1.Float() == v2.Float()
-
 		case reflect.Complex64, reflect.Complex128:
 			return v1.Complex() == v2.Complex()
 
@@ -82,6 +212,9 @@ func isEquals(ruleParam, eventParam interface{}) bool {
 			return v1.String() == v2.String()
 
 		case reflect.Slice:
+			if v1.Len() != v2.Len() {
+				return false
+			}
 			for i := 0; i < v1.Len(); i++ {
 				if !isEquals(v1.Index(i).Interface(), v2.Index(i).Interface()) {
 					return false
@@ -118,126 +251,386 @@ func isNotEquals(ruleParam, eventParam interface{}) bool {
 }
 
 func isGreaterThan(ruleParam, eventParam interface{}) bool {
+	return compareNumeric(ruleParam, eventParam, func(c int) bool { return c > 0 })
+}
 
-	v1 := reflect.ValueOf(ruleParam)
-	v2 := reflect.ValueOf(eventParam)
+func isLessThan(ruleParam, eventParam interface{}) bool {
+	return compareNumeric(ruleParam, eventParam, func(c int) bool { return c < 0 })
+}
 
-	if v1.Kind() == v2.Kind() {
-		switch v1.Kind() {
+func isGreaterThanOrEqual(ruleParam, eventParam interface{}) bool {
+	return compareNumeric(ruleParam, eventParam, func(c int) bool { return c >= 0 })
+}
 
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return v2.Int() > v1.Int()
+func isLessThanOrEqual(ruleParam, eventParam interface{}) bool {
+	return compareNumeric(ruleParam, eventParam, func(c int) bool { return c <= 0 })
+}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			return v2.Uint() > v1.Uint()
+func isBetween(ruleParam, eventParam interface{}) bool {
+	r := ruleParam.(numberRange)
 
-		case reflect.Float32, reflect.Float64:
-			return v2.Float() > v1.Float()
+	switch eventParam := eventParam.(type) {
+	case []interface{}:
+		// a single element has to be within the range, bounds can not be satisfied by different elements
+		for _, eachElement := range eventParam {
+			if isBetween(ruleParam, eachElement) {
+				return true
+			}
+		}
+		return false
+	}
+	if r.exclusive {
+		return isGreaterThan(r.low, eventParam) && isLessThan(r.high, eventParam)
+	}
+	return isGreaterThanOrEqual(r.low, eventParam) && isLessThanOrEqual(r.high, eventParam)
+}
 
+func isInArray(value interface{}, array []interface{}) bool {
+	for _, element := range array {
+		if isEquals(value, element) {
+			return true
 		}
 	}
+	return false
+}
+
+// asStringValue returns value as string if it is of a string kind, json.Number excepted as it is a number.
+func asStringValue(value interface{}) (string, bool) {
+	if _, ok := value.(json.Number); ok {
+		return "", false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.String {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func isStartingWith(ruleParam, eventParam interface{}) bool {
+	prefix, ok1 := asStringValue(ruleParam)
+	text, ok2 := asStringValue(eventParam)
+	return ok1 && ok2 && strings.HasPrefix(text, prefix)
+}
+
+func isEndingWith(ruleParam, eventParam interface{}) bool {
+	suffix, ok1 := asStringValue(ruleParam)
+	text, ok2 := asStringValue(eventParam)
+	return ok1 && ok2 && strings.HasSuffix(text, suffix)
+}
+
+func isRegexMatch(ruleParam, eventParam interface{}) bool {
+	if text, ok := asStringValue(eventParam); ok {
+		return ruleParam.(*regexp.Regexp).MatchString(text)
+	}
+	return false
+}
+
+func isNotRegexMatch(ruleParam, eventParam interface{}) bool {
+	return !isRegexMatch(ruleParam, eventParam)
+}
+
+func isContaining(ruleParam, eventParam interface{}) bool {
 	switch eventParam := eventParam.(type) {
+	case string:
+		return strings.Contains(eventParam, ruleParam.(string))
 	case []interface{}:
 		for _, eachElement := range eventParam {
-			if isGreaterThan(ruleParam, eachElement) {
+			if isContaining(ruleParam, eachElement) {
 				return true
 			}
 		}
+	}
+	return false
+}
+
+func isNotContaining(ruleParam, eventParam interface{}) bool {
+	return !isContaining(ruleParam, eventParam)
+}
 
+func isContainingAnyOf(ruleParam, eventParam interface{}) bool {
+	switch eventParam := eventParam.(type) {
+	case string:
+		return ruleParam.(*ahoCorasick).matchString(eventParam)
+	case []interface{}:
+		for _, eachElement := range eventParam {
+			if isContainingAnyOf(ruleParam, eachElement) {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-func isLessThan(ruleParam, eventParam interface{}) bool {
-	v1 := reflect.ValueOf(ruleParam)
-	v2 := reflect.ValueOf(eventParam)
+func isIPInRange(ruleParam, eventParam interface{}) bool {
+	return matchAddr(eventParam, ruleParam.(netip.Prefix).Contains)
+}
 
-	if v1.Kind() == v2.Kind() {
-		switch v1.Kind() {
+func isIPInAnyRange(ruleParam, eventParam interface{}) bool {
+	return matchAddr(eventParam, ruleParam.(*prefixTrie).contains)
+}
 
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return v2.Int() < v1.Int()
+// isIPOfClass returns an operator matching IP addresses for which class returns the rule value.
+func isIPOfClass(class func(addr netip.Addr) bool) OperatorFunc {
+	return func(ruleParam, eventParam interface{}) bool {
+		return matchAddr(eventParam, func(addr netip.Addr) bool { return class(addr) == ruleParam.(bool) })
+	}
+}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			return v2.Uint() < v1.Uint()
+// isPublicAddr reports whether addr is a global unicast address outside of the private ranges.
+func isPublicAddr(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
 
-		case reflect.Float32, reflect.Float64:
-			return v2.Float() < v1.Float()
+func isIPOfVersion(ruleParam, eventParam interface{}) bool {
+	return matchAddr(eventParam, func(addr netip.Addr) bool { return addr.Is4() == (ruleParam.(int) == 4) })
+}
 
+// matchAddr parses eventParam as an IP address and checks it using match.
+// If eventParam is an array, it returns true if any of its elements matches.
+func matchAddr(eventParam interface{}, match func(addr netip.Addr) bool) bool {
+	switch eventParam := eventParam.(type) {
+	case string:
+		addr, ok := parseAddr(eventParam)
+		return ok && match(addr)
+	case []interface{}:
+		for _, eachElement := range eventParam {
+			if matchAddr(eachElement, match) {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// parseAddr parses an IPv4 or IPv6 address, IPv4-mapped IPv6 addresses are converted to IPv4
+// and zones are dropped so that addresses can be matched against prefixes.
+func parseAddr(str string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(str)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func isExisting(ruleParam, eventParam interface{}) bool {
+	return ruleParam.(bool) != isMissing(eventParam)
+}
+
+func isNotExisting(ruleParam, eventParam interface{}) bool {
+	return !isExisting(ruleParam, eventParam)
+}
+
+func isNullValue(ruleParam, eventParam interface{}) bool {
+	return ruleParam.(bool) == (eventParam == nil)
+}
+
+func isOfType(ruleParam, eventParam interface{}) bool {
+	if isMissing(eventParam) || eventParam == nil {
+		return false
+	}
+	return ruleParam.(string) == jsonTypeOf(eventParam)
+}
+
+// jsonTypeOf returns the name of the JSON type the value would be encoded as.
+func jsonTypeOf(value interface{}) string {
+	if _, ok := value.(json.Number); ok {
+		return numberType
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return stringType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32,
+		reflect.Float64:
+		return numberType
+	case reflect.Bool:
+		return boolType
+	case reflect.Slice, reflect.Array:
+		return arrayType
+	case reflect.Map:
+		return objectType
+	}
+	return ""
+}
+
+func isInSet(ruleParam, eventParam interface{}) bool {
+	set := ruleParam.(valueSet)
 	switch eventParam := eventParam.(type) {
 	case []interface{}:
 		for _, eachElement := range eventParam {
-			if isLessThan(ruleParam, eachElement) {
+			if set.contains(eachElement) {
 				return true
 			}
 		}
-
+		return false
 	}
-	return false
+	return set.contains(eventParam)
 }
 
-func isInArray(value interface{}, array []interface{}) bool {
-	for _, element := range array {
-		if isEquals(value, element) {
+func isNotInSet(ruleParam, eventParam interface{}) bool {
+	return !isInSet(ruleParam, eventParam)
+}
+
+func isContainingAny(ruleParam, eventParam interface{}) bool {
+	if isMissing(eventParam) {
+		return false
+	}
+	for _, eachElement := range asArray(eventParam) {
+		if ruleParam.(valueSet).contains(eachElement) {
 			return true
 		}
 	}
 	return false
 }
 
-func isStartingWith(ruleParam, eventParam interface{}) bool {
-	v1 := reflect.ValueOf(ruleParam)
-	v2 := reflect.ValueOf(eventParam)
-	if v1.Kind() == v2.Kind() {
-		switch v1.Kind() {
-		case reflect.String:
-			return strings.HasPrefix(v2.String(), v1.String())
+func isContainingAll(ruleParam, eventParam interface{}) bool {
+	if isMissing(eventParam) {
+		return false
+	}
+	set := ruleParam.(valueSet)
+	found := make(valueSet, len(set))
+	for _, eachElement := range asArray(eventParam) {
+		if key, ok := setKey(eachElement); ok {
+			if _, inSet := set[key]; inSet {
+				found[key] = struct{}{}
+			}
 		}
 	}
-	return false
+	return len(found) == len(set)
 }
 
-func isEndingWith(ruleParam, eventParam interface{}) bool {
-	v1 := reflect.ValueOf(ruleParam)
-	v2 := reflect.ValueOf(eventParam)
-	if v1.Kind() == v2.Kind() {
-		switch v1.Kind() {
-		case reflect.String:
-			return strings.HasSuffix(v2.String(), v1.String())
+// matchFold case folds eventParam and checks it against the already folded ruleParam using match.
+// If eventParam is an array, it returns true if any of its elements matches.
+func matchFold(ruleParam, eventParam interface{}, match func(eventStr, ruleStr string) bool) bool {
+	switch eventParam := eventParam.(type) {
+	case string:
+		return match(foldString(eventParam), ruleParam.(string))
+	case []interface{}:
+		for _, eachElement := range eventParam {
+			if matchFold(ruleParam, eachElement, match) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-func isRegexMatch(ruleParam, eventParam interface{}) bool {
-	v1 := reflect.ValueOf(ruleParam)
-	v2 := reflect.ValueOf(eventParam)
-	if v2.Kind() == reflect.String {
-		re := v1.Interface().(*regexp.Regexp)
-		text := v2.String()
-		return re.MatchString(text)
+func isEqualFold(ruleParam, eventParam interface{}) bool {
+	return matchFold(ruleParam, eventParam, func(eventStr, ruleStr string) bool { return eventStr == ruleStr })
+}
+
+func isStartingWithFold(ruleParam, eventParam interface{}) bool {
+	return matchFold(ruleParam, eventParam, strings.HasPrefix)
+}
+
+func isEndingWithFold(ruleParam, eventParam interface{}) bool {
+	return matchFold(ruleParam, eventParam, strings.HasSuffix)
+}
+
+func isContainingFold(ruleParam, eventParam interface{}) bool {
+	return matchFold(ruleParam, eventParam, strings.Contains)
+}
+
+// matchTime parses eventParam as a time and checks it using match.
+// If eventParam is an array, it returns true if any of its elements matches.
+func matchTime(eventParam interface{}, match func(t time.Time) bool) bool {
+	if t, ok := asEventTime(eventParam); ok {
+		return match(t)
+	}
+	switch eventParam := eventParam.(type) {
+	case []interface{}:
+		for _, eachElement := range eventParam {
+			if matchTime(eachElement, match) {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-func isNotRegexMatch(ruleParam, eventParam interface{}) bool {
-	return !isRegexMatch(ruleParam, eventParam)
+func isBefore(ruleParam, eventParam interface{}) bool {
+	return matchTime(eventParam, func(t time.Time) bool { return t.Before(ruleParam.(time.Time)) })
 }
 
-func isIPInRange(ruleParam, eventParam interface{}) bool {
+func isAfter(ruleParam, eventParam interface{}) bool {
+	return matchTime(eventParam, func(t time.Time) bool { return t.After(ruleParam.(time.Time)) })
+}
 
-	v2 := reflect.ValueOf(eventParam)
-	v1 := reflect.ValueOf(ruleParam)
+func isWithinLast(ruleParam, eventParam interface{}) bool {
+	return withinLastAt(time.Now)(ruleParam, eventParam)
+}
 
-	if v2.Kind() == reflect.String {
-		ip := net.ParseIP(v2.String())
-		if ip == nil {
-			return false
+// withinLastAt returns the withinLast operator, taking the current time from clock.
+func withinLastAt(clock func() time.Time) OperatorFunc {
+	return func(ruleParam, eventParam interface{}) bool {
+		now := clock()
+		return matchTime(eventParam, func(t time.Time) bool {
+			return !t.After(now) && !t.Before(now.Add(-ruleParam.(time.Duration)))
+		})
+	}
+}
+
+func isOnDayOfWeek(ruleParam, eventParam interface{}) bool {
+	w := ruleParam.(weekdays)
+	return matchTime(eventParam, func(t time.Time) bool { return w.days[t.In(w.location).Weekday()] })
+}
+
+func isHourBetween(ruleParam, eventParam interface{}) bool {
+	r := ruleParam.(hourRange)
+	return matchTime(eventParam, func(t time.Time) bool {
+		hour := t.In(r.location).Hour()
+		if r.from <= r.to {
+			return hour >= r.from && hour < r.to
 		}
-		subnetStr := v1.Interface().(*net.IPNet)
-		return subnetStr.Contains(ip)
+		// range wraps around midnight, e.g. from 22 to 6
+		return hour >= r.from || hour < r.to
+	})
+}
+
+// asEventTime parses value as an RFC3339 timestamp if it is a string, or as epoch time if it is a number.
+// Epoch times are treated as milliseconds if they are too large to be seconds of a realistic date.
+func asEventTime(value interface{}) (time.Time, bool) {
+	if str, ok := value.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, str)
+		return t, err == nil
 	}
-	return false
+	n, ok := asNumberValue(value)
+	if !ok {
+		return time.Time{}, false
+	}
+	epoch := n.float()
+	if math.Abs(epoch) >= epochMillisThreshold {
+		return time.UnixMilli(int64(epoch)).UTC(), true
+	}
+	seconds, fraction := math.Modf(epoch)
+	return time.Unix(int64(seconds), int64(fraction*1e9)).UTC(), true
+}
+
+// epochMillisThreshold is the smallest epoch value treated as milliseconds, as seconds it is beyond the year 33000.
+const epochMillisThreshold = 1e12
+
+// lengthOf returns the number of elements of an array or the number of characters of a string.
+func lengthOf(value interface{}) (int, bool) {
+	switch value := value.(type) {
+	case []interface{}:
+		return len(value), true
+	case string:
+		return utf8.RuneCountInString(value), true
+	}
+	return 0, false
+}
+
+func isLengthEqual(ruleParam, eventParam interface{}) bool {
+	l, ok := lengthOf(eventParam)
+	return ok && isEquals(ruleParam, l)
+}
+
+func isLengthGreaterThan(ruleParam, eventParam interface{}) bool {
+	l, ok := lengthOf(eventParam)
+	return ok && isGreaterThan(ruleParam, l)
+}
+
+func isLengthLessThan(ruleParam, eventParam interface{}) bool {
+	l, ok := lengthOf(eventParam)
+	return ok && isLessThan(ruleParam, l)
 }
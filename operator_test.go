@@ -1,6 +1,10 @@
 package jsontology
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
 
 func TestIsEquals(t *testing.T) {
 	tests := []struct {
@@ -24,6 +28,14 @@ func TestIsEquals(t *testing.T) {
 		{map[string]int{"a": 1}, map[string]int{"a": 2}, false, "Map Value Difference"},
 		{42, []interface{}{1, 2, 42}, true, "Fallback Array Match"},
 		{99, []interface{}{1, 2, 42}, false, "Fallback Array NoMatch"},
+		{42, 42.0, true, "Int Float Equality"},
+		{uint8(42), int64(42), true, "Uint Int Equality"},
+		{42, json.Number("42"), true, "Int Json Number Equality"},
+		{42.5, json.Number("42.5"), true, "Float Json Number Equality"},
+		{"42", json.Number("42"), false, "String Json Number Inequality"},
+		{int64(9007199254740993), float64(9007199254740992), false, "Large Int Float Inequality"},
+		{int64(9007199254740993), json.Number("9007199254740993"), true, "Large Int Json Number Equality"},
+		{uint64(18446744073709551615), int64(-1), false, "Max Uint Negative Int Inequality"},
 	}
 
 	for _, test := range tests {
@@ -83,6 +95,10 @@ func TestIsGreaterThan(t *testing.T) {
 		{20.5, 10.5, false, "Greater Than Float Inequality"},
 		{42, []interface{}{10, 20, 30}, false, "Greater Than Fallback Array No Match"},
 		{42, []interface{}{50, 60}, true, "Greater Than Fallback Array Match"},
+		{499, 499.5, true, "Greater Than Int Float"},
+		{uint(10), -5, false, "Greater Than Uint Negative Int"},
+		{int64(9007199254740992), json.Number("9007199254740993"), true, "Greater Than Large Int Json Number"},
+		{-1.5, int64(-1), true, "Greater Than Negative Float Int"},
 	}
 
 	for _, test := range tests {
@@ -108,6 +124,9 @@ func TestIsLessThan(t *testing.T) {
 		{10.5, 20.5, false, "Less Than Float Inequality"},
 		{42, []interface{}{50, 60, 70}, false, "Less Than Fallback Array No Match"},
 		{42, []interface{}{10, 20}, true, "Less Than Fallback Array Match"},
+		{500.0, 499, true, "Less Than Float Int"},
+		{int64(9007199254740993), float64(9007199254740992), true, "Less Than Large Int Float"},
+		{uint64(18446744073709551615), 1e30, false, "Less Than Max Uint Huge Float"},
 	}
 
 	for _, test := range tests {
@@ -119,3 +138,426 @@ func TestIsLessThan(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldPresenceOperators(t *testing.T) {
+	tests := []struct {
+		operator   OperatorFunc
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isExisting, true, "value", true, "Exists Present"},
+		{isExisting, true, nil, true, "Exists Null"},
+		{isExisting, true, keyNotFound, false, "Exists Missing"},
+		{isExisting, false, keyNotFound, true, "Exists False Missing"},
+		{isExisting, true, "keyNotFound", true, "Exists Sentinel Lookalike"},
+		{isNotExisting, true, keyNotFound, true, "Not Exists Missing"},
+		{isNotExisting, true, 1.0, false, "Not Exists Present"},
+		{isNullValue, true, nil, true, "Is Null Null"},
+		{isNullValue, true, keyNotFound, false, "Is Null Missing"},
+		{isNullValue, false, "value", true, "Is Null False Present"},
+		{isEquals, "keyNotFound", keyNotFound, false, "Equals Sentinel Lookalike"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsOfType(t *testing.T) {
+	tests := []struct {
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{"string", "hello", true, "String Type"},
+		{"number", 3.14, true, "Float Type"},
+		{"number", 42, true, "Int Type"},
+		{"number", "42", false, "String Is Not Number"},
+		{"bool", false, true, "Bool Type"},
+		{"array", []interface{}{1, 2}, true, "Array Type"},
+		{"object", map[string]interface{}{"a": 1}, true, "Object Type"},
+		{"object", nil, false, "Null Is Not Object"},
+		{"string", keyNotFound, false, "Missing Has No Type"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := isOfType(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("isOfType(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestSetOperators(t *testing.T) {
+	set, err := asValueSet([]interface{}{"a", "b", 1.0, true, nil, json.Number("9007199254740993")})
+	if err != nil {
+		t.Fatal("unable to build value set, received error", err)
+	}
+	tests := []struct {
+		operator   OperatorFunc
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isInSet, "a", true, "In String"},
+		{isInSet, 1, true, "In Int Matches Float"},
+		{isInSet, nil, true, "In Null"},
+		{isInSet, "c", false, "In No Match"},
+		{isInSet, []interface{}{"c", "b"}, true, "In Fallback Array Match"},
+		{isInSet, keyNotFound, false, "In Missing"},
+		{isInSet, uint16(1), true, "In Uint Matches Float"},
+		{isInSet, int64(9007199254740993), true, "In Large Int"},
+		{isInSet, int64(9007199254740992), false, "In Large Int No Match"},
+		{isNotInSet, "c", true, "Not In No Match"},
+		{isNotInSet, "a", false, "Not In Match"},
+		{isContainingAny, []interface{}{"x", true}, true, "Contains Any Match"},
+		{isContainingAny, []interface{}{"x", "y"}, false, "Contains Any No Match"},
+		{isContainingAll, []interface{}{"a", "b", 1.0, true, nil, int64(9007199254740993), "x"}, true, "Contains All Match"},
+		{isContainingAll, []interface{}{"a", "b", "a", 1.0, true}, false, "Contains All Partial"},
+		{isContainingAll, keyNotFound, false, "Contains All Missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator(set, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", set, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsGreaterThanOrEqual(t *testing.T) {
+	tests := []struct {
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{10, 10, true, "Greater Equal Int Equality"},
+		{10, 20, true, "Greater Equal Int"},
+		{20, 10, false, "Greater Equal Int Inequality"},
+		{499.5, 499.5, true, "Greater Equal Float Equality"},
+		{42, []interface{}{10, 42}, true, "Greater Equal Fallback Array Match"},
+		{42, []interface{}{10, 20}, false, "Greater Equal Fallback Array No Match"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := isGreaterThanOrEqual(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("isGreaterThanOrEqual(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsLessThanOrEqual(t *testing.T) {
+	tests := []struct {
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{10, 10, true, "Less Equal Int Equality"},
+		{20, 10, true, "Less Equal Int"},
+		{10, 20, false, "Less Equal Int Inequality"},
+		{499.5, 499.5, true, "Less Equal Float Equality"},
+		{42, []interface{}{50, 42}, true, "Less Equal Fallback Array Match"},
+		{42, []interface{}{50, 60}, false, "Less Equal Fallback Array No Match"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := isLessThanOrEqual(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("isLessThanOrEqual(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsBetween(t *testing.T) {
+	tests := []struct {
+		ruleParam  []interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{[]interface{}{500.0, 599.0}, 500.0, true, "Between Lower Bound"},
+		{[]interface{}{500.0, 599.0}, 599.0, true, "Between Upper Bound"},
+		{[]interface{}{500.0, 599.0}, 404.0, false, "Between Outside"},
+		{[]interface{}{500.0, 599.0, true}, 500.0, false, "Between Exclusive Lower Bound"},
+		{[]interface{}{500.0, 599.0, true}, 550.5, true, "Between Exclusive Inside"},
+		{[]interface{}{500.0, 599.0}, []interface{}{404.0, 503.0}, true, "Between Fallback Array Match"},
+		{[]interface{}{500.0, 599.0}, []interface{}{404.0, 700.0}, false, "Between Fallback Array Split Bounds"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ruleParam, err := asNumberRange(test.ruleParam)
+			if err != nil {
+				t.Fatal("unable to build number range, received error", err)
+			}
+			result := isBetween(ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("isBetween(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestCaseInsensitiveOperators(t *testing.T) {
+	tests := []struct {
+		operator   OperatorFunc
+		ruleParam  string
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isEqualFold, "Admin", "ADMIN", true, "Equal Fold ASCII"},
+		{isEqualFold, "Admin", "admins", false, "Equal Fold ASCII Inequality"},
+		{isEqualFold, "STRASSE", "straße", true, "Equal Fold Full Folding"},
+		{isEqualFold, "CAFÉ", "cafe\u0301", true, "Equal Fold Decomposed"},
+		{isEqualFold, "admin", []interface{}{"root", "ADMIN"}, true, "Equal Fold Fallback Array Match"},
+		{isEqualFold, "admin", keyNotFound, false, "Equal Fold Missing"},
+		{isStartingWithFold, "ADM", "admin", true, "Starts With Fold"},
+		{isStartingWithFold, "MIN", "admin", false, "Starts With Fold No Match"},
+		{isEndingWithFold, "MIN", "Admin", true, "Ends With Fold"},
+		{isContainingFold, "DMI", "Admin", true, "Contains Fold"},
+		{isContainingFold, "ÖL", "Motoröl", true, "Contains Fold Unicode"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ruleParam, err := asFoldedString(test.ruleParam)
+			if err != nil {
+				t.Fatal("unable to fold string, received error", err)
+			}
+			result := test.operator(ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsContaining(t *testing.T) {
+	tests := []struct {
+		operator   OperatorFunc
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isContaining, "failed password for root", true, "Contains"},
+		{isContaining, "accepted password for root", false, "Contains No Match"},
+		{isContaining, []interface{}{"ok", "login failed"}, true, "Contains Fallback Array Match"},
+		{isContaining, 42.0, false, "Contains Not String"},
+		{isNotContaining, "accepted password for root", true, "Not Contains"},
+		{isNotContaining, keyNotFound, true, "Not Contains Missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator("failed", test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", "failed", test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsContainingAnyOf(t *testing.T) {
+	matcher, err := asKeywordMatcher([]interface{}{"mimikatz", "psexec", "rubeus"})
+	if err != nil {
+		t.Fatal("unable to build keyword matcher, received error", err)
+	}
+	tests := []struct {
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{"c:\\tools\\psexec.exe -s cmd", true, "Contains Any Of"},
+		{"notepad.exe", false, "Contains Any Of No Match"},
+		{[]interface{}{"cmd.exe", "rubeus.exe asktgt"}, true, "Contains Any Of Fallback Array Match"},
+		{keyNotFound, false, "Contains Any Of Missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := isContainingAnyOf(matcher, test.eventParam)
+			if result != test.expected {
+				t.Errorf("isContainingAnyOf(%v) = %v; want %v", test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestTimeOperators(t *testing.T) {
+	isWithinLast := withinLastAt(func() time.Time { return time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC) })
+
+	parse := func(handler OperatorTypeHandlerFunc, value interface{}) interface{} {
+		parsed, err := handler(value)
+		if err != nil {
+			t.Fatal("unable to parse rule value, received error", err)
+		}
+		return parsed
+	}
+	tests := []struct {
+		operator   OperatorFunc
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isBefore, parse(asTime, "2024-09-15T00:00:00Z"), "2024-09-14T23:59:59Z", true, "Before RFC3339"},
+		{isBefore, parse(asTime, "2024-09-15T00:00:00Z"), "2024-09-15T01:00:00+02:00", true, "Before RFC3339 Offset"},
+		{isBefore, parse(asTime, "2024-09-15T00:00:00Z"), 1726358400.0, false, "Before Epoch Seconds Equal"},
+		{isAfter, parse(asTime, 1726358400), 1726358400001.0, true, "After Epoch Millis"},
+		{isAfter, parse(asTime, "2024-09-15T00:00:00Z"), "yesterday", false, "After Invalid Time"},
+		{isAfter, parse(asTime, "2024-09-15T00:00:00Z"), []interface{}{"2024-01-01T00:00:00Z", "2024-12-01T00:00:00Z"}, true, "After Fallback Array Match"},
+		{isWithinLast, parse(asDuration, "15m"), "2024-09-15T11:50:00Z", true, "Within Last"},
+		{isWithinLast, parse(asDuration, "15m"), "2024-09-15T11:40:00Z", false, "Within Last Too Old"},
+		{isWithinLast, parse(asDuration, "15m"), "2024-09-15T12:05:00Z", false, "Within Last Future"},
+		{isOnDayOfWeek, parse(asWeekdays, []interface{}{"Sat", "sunday"}), "2024-09-15T12:00:00Z", true, "Day Of Week"},
+		{isOnDayOfWeek, parse(asWeekdays, []interface{}{"sat", "sun"}), "2024-09-16T12:00:00Z", false, "Day Of Week No Match"},
+		{isOnDayOfWeek, parse(asWeekdays, map[string]interface{}{"days": []interface{}{"mon"}, "timezone": "Asia/Tokyo"}), "2024-09-15T20:00:00Z", true, "Day Of Week Timezone"},
+		{isHourBetween, parse(asHourRange, []interface{}{9.0, 17.0}), "2024-09-15T09:00:00Z", true, "Hour Between Lower Bound"},
+		{isHourBetween, parse(asHourRange, []interface{}{9.0, 17.0}), "2024-09-15T17:00:00Z", false, "Hour Between Upper Bound"},
+		{isHourBetween, parse(asHourRange, []interface{}{22.0, 6.0}), "2024-09-15T03:00:00Z", true, "Hour Between Wraps Midnight"},
+		{isHourBetween, parse(asHourRange, []interface{}{22.0, 6.0}), "2024-09-15T12:00:00Z", false, "Hour Between Wraps Midnight No Match"},
+		{isHourBetween, parse(asHourRange, map[string]interface{}{"from": 9.0, "to": 17.0, "timezone": "America/New_York"}), "2024-09-15T14:00:00Z", true, "Hour Between Timezone"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIPRangeOperators(t *testing.T) {
+	parse := func(handler OperatorTypeHandlerFunc, value interface{}) interface{} {
+		parsed, err := handler(value)
+		if err != nil {
+			t.Fatal("unable to parse rule value, received error", err)
+		}
+		return parsed
+	}
+	anyRange := parse(asPrefixTrie, []interface{}{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "fe80::/10"})
+	tests := []struct {
+		operator   OperatorFunc
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isIPInRange, parse(asIpNet, "10.0.0.0/8"), "10.1.2.3", true, "In Range IPv4"},
+		{isIPInRange, parse(asIpNet, "10.0.0.0/8"), "11.1.2.3", false, "In Range IPv4 Outside"},
+		{isIPInRange, parse(asIpNet, "10.1.2.3/8"), "10.200.0.1", true, "In Range Unmasked CIDR"},
+		{isIPInRange, parse(asIpNet, "10.0.0.0/8"), "::ffff:10.1.2.3", true, "In Range IPv4 Mapped"},
+		{isIPInRange, parse(asIpNet, "2001:db8::/32"), "2001:db8:1::1", true, "In Range IPv6 Compressed"},
+		{isIPInRange, parse(asIpNet, "2001:db8::/32"), "10.1.2.3", false, "In Range Other Family"},
+		{isIPInRange, parse(asIpNet, "192.168.1.1"), "192.168.1.1", true, "In Range Single IP"},
+		{isIPInRange, parse(asIpNet, "192.168.1.1"), "192.168.1.2", false, "In Range Single IP Outside"},
+		{isIPInRange, parse(asIpNet, "10.0.0.0/8"), "not an ip", false, "In Range Invalid IP"},
+		{isIPInRange, parse(asIpNet, "10.0.0.0/8"), []interface{}{"1.1.1.1", "10.0.0.1"}, true, "In Range Fallback Array Match"},
+		{isIPInAnyRange, anyRange, "10.9.9.9", true, "In Any Range IPv4"},
+		{isIPInAnyRange, anyRange, "192.168.1.1", true, "In Any Range Single IP"},
+		{isIPInAnyRange, anyRange, "192.168.1.2", false, "In Any Range Outside"},
+		{isIPInAnyRange, anyRange, "fe80::1%eth0", true, "In Any Range IPv6 Zone"},
+		{isIPInAnyRange, anyRange, "2001:db9::1", false, "In Any Range IPv6 Outside"},
+		{isIPInAnyRange, anyRange, keyNotFound, false, "In Any Range Missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIPClassOperators(t *testing.T) {
+	tests := []struct {
+		operator   Operator
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isPrivateIP, true, "192.168.1.10", true, "Private IPv4"},
+		{isPrivateIP, true, "172.32.0.1", false, "Private IPv4 Outside Block"},
+		{isPrivateIP, true, "fd00::1", true, "Private IPv6"},
+		{isPrivateIP, false, "8.8.8.8", true, "Not Private"},
+		{isPrivateIP, false, "not an ip", false, "Not Private Invalid IP"},
+		{isPrivateIP, true, []interface{}{"8.8.8.8", "10.0.0.1"}, true, "Private Fallback Array Match"},
+		{isPublicIP, true, "8.8.8.8", true, "Public IPv4"},
+		{isPublicIP, true, "2606:4700::1111", true, "Public IPv6"},
+		{isPublicIP, true, "10.0.0.1", false, "Public Private IP"},
+		{isPublicIP, true, "127.0.0.1", false, "Public Loopback"},
+		{isLoopback, true, "::1", true, "Loopback IPv6"},
+		{isLoopback, true, "127.10.0.1", true, "Loopback IPv4"},
+		{isLinkLocal, true, "169.254.1.1", true, "Link Local IPv4"},
+		{isLinkLocal, true, "fe80::1", true, "Link Local IPv6"},
+		{isMulticast, true, "224.0.0.251", true, "Multicast IPv4"},
+		{isMulticast, true, "ff02::1", true, "Multicast IPv6"},
+		{isMulticast, true, keyNotFound, false, "Multicast Missing"},
+		{ipVersion, 4, "10.0.0.1", true, "Version 4"},
+		{ipVersion, 4, "::ffff:10.0.0.1", true, "Version 4 Mapped"},
+		{ipVersion, 6, "10.0.0.1", false, "Version 6 IPv4"},
+		{ipVersion, 6, []interface{}{"10.0.0.1", "::1"}, true, "Version 6 Fallback Array Match"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := operatorFuncMapping[test.operator](test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("%s(%v, %v) = %v; want %v", test.operator, test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestLengthOperators(t *testing.T) {
+	tests := []struct {
+		operator   OperatorFunc
+		ruleParam  interface{}
+		eventParam interface{}
+		expected   bool
+		name       string
+	}{
+		{isLengthEqual, 3.0, []interface{}{1, 2, 3}, true, "Length Array"},
+		{isLengthEqual, 4.0, "héllo", false, "Length String Inequality"},
+		{isLengthEqual, 5.0, "héllo", true, "Length String Counts Characters"},
+		{isLengthEqual, 0.0, keyNotFound, false, "Length Missing"},
+		{isLengthEqual, 1.0, 42.0, false, "Length Number"},
+		{isLengthGreaterThan, 5.0, []interface{}{1, 2, 3, 4, 5, 6}, true, "Length Greater Than"},
+		{isLengthGreaterThan, 5.0, []interface{}{1, 2, 3, 4, 5}, false, "Length Greater Than Equal"},
+		{isLengthLessThan, 3.0, "ab", true, "Length Less Than"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.operator(test.ruleParam, test.eventParam)
+			if result != test.expected {
+				t.Errorf("operator(%v, %v) = %v; want %v", test.ruleParam, test.eventParam, result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package jsontology
+
+import "net/netip"
+
+// prefixTrie is a binary trie of IP prefixes, looking up whether an address is contained in any
+// of its prefixes in time proportional to the address length, regardless of the number of prefixes.
+type prefixTrie struct {
+	v4 *prefixTrieNode
+	v6 *prefixTrieNode
+}
+
+type prefixTrieNode struct {
+	children [2]*prefixTrieNode
+	// terminal is true if a prefix ends at this node, in which case all addresses below it are contained
+	terminal bool
+}
+
+// newPrefixTrie builds a trie holding the given prefixes.
+func newPrefixTrie(prefixes []netip.Prefix) *prefixTrie {
+	t := &prefixTrie{v4: &prefixTrieNode{}, v6: &prefixTrieNode{}}
+	for _, prefix := range prefixes {
+		t.insert(prefix)
+	}
+	return t
+}
+
+func (t *prefixTrie) insert(prefix netip.Prefix) {
+	node := t.root(prefix.Addr())
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		if node.terminal {
+			// a shorter prefix already contains this one
+			return
+		}
+		bit := addressBit(bytes, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &prefixTrieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.terminal = true
+	// longer prefixes below this node are now redundant
+	node.children = [2]*prefixTrieNode{}
+}
+
+// contains reports whether addr is contained in any prefix of the trie.
+func (t *prefixTrie) contains(addr netip.Addr) bool {
+	node := t.root(addr)
+	bytes := addr.AsSlice()
+	for i := 0; i < addr.BitLen(); i++ {
+		if node.terminal {
+			return true
+		}
+		node = node.children[addressBit(bytes, i)]
+		if node == nil {
+			return false
+		}
+	}
+	return node.terminal
+}
+
+func (t *prefixTrie) root(addr netip.Addr) *prefixTrieNode {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// addressBit returns the i-th most significant bit of the address bytes.
+func addressBit(bytes []byte, i int) int {
+	return int(bytes[i/8]>>(7-i%8)) & 1
+}
@@ -0,0 +1,76 @@
+package jsontology
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"testing"
+)
+
+func TestPrefixTrieMatchesLinearScan(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomAddr := func(is4 bool) netip.Addr {
+		if is4 {
+			var b [4]byte
+			random.Read(b[:])
+			// keep addresses close together so that prefixes overlap
+			b[0] = 10
+			return netip.AddrFrom4(b)
+		}
+		var b [16]byte
+		random.Read(b[:])
+		b[0], b[1] = 0x20, 0x01
+		return netip.AddrFrom16(b)
+	}
+
+	prefixes := []netip.Prefix{}
+	for i := 0; i < 200; i++ {
+		addr := randomAddr(i%2 == 0)
+		bits := 8 + random.Intn(addr.BitLen()-7)
+		prefixes = append(prefixes, netip.PrefixFrom(addr, bits).Masked())
+	}
+	trie := newPrefixTrie(prefixes)
+
+	for i := 0; i < 5000; i++ {
+		addr := randomAddr(i%2 == 0)
+		expected := false
+		for _, prefix := range prefixes {
+			expected = expected || prefix.Contains(addr)
+		}
+		if result := trie.contains(addr); result != expected {
+			t.Fatalf("contains(%v) = %v; want %v", addr, result, expected)
+		}
+	}
+}
+
+func BenchmarkIPInAnyRange(b *testing.B) {
+	ranges := make([]interface{}, 5000)
+	for i := range ranges {
+		ranges[i] = fmt.Sprintf("10.%d.%d.0/24", i/256, i%256)
+	}
+	trie, err := asPrefixTrie(ranges)
+	if err != nil {
+		b.Fatal("unable to build prefix trie, received error", err)
+	}
+	prefixes := make([]netip.Prefix, len(ranges))
+	for i, eachRange := range ranges {
+		prefixes[i] = netip.MustParsePrefix(eachRange.(string))
+	}
+	ip := "10.19.135.7"
+
+	b.Run("prefixTrie", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			isIPInAnyRange(trie, ip)
+		}
+	})
+	b.Run("linearScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			addr := netip.MustParseAddr(ip)
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					break
+				}
+			}
+		}
+	})
+}
@@ -1,14 +1,66 @@
 package jsontology
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"io"
+	"time"
 )
 
 type Rule struct {
+	id         string
 	condition  [][]constraint
-	onMatch    eventHandler
+	onMatch    EventHandler
 	extraParam map[string]interface{}
+	// branches are the compiled branches of condition, in the order given in the rule
+	branches []*compiledConstraint
+	// memoizeFields is true if the conditions look up a field of the normalized data more than once
+	memoizeFields bool
+}
+
+type ruleOptions struct {
+	id              string
+	normalizeString func(string) string
+	caseFolding     bool
+	clock           func() time.Time
+}
+
+// RuleOption configures optional settings of a rule.
+type RuleOption func(*ruleOptions)
+
+// WithID sets the ID identifying the rule, e.g. in the Match passed to its event handler.
+// Without it, the "rule_id" param of the rule is used as ID, if given.
+func WithID(id string) RuleOption {
+	return func(o *ruleOptions) {
+		o.id = id
+	}
+}
+
+// WithUnicodeNormalization brings the strings compared by string operators (e.g. eq, sw, rgx, in) to Unicode NFC form,
+// both in the rule values and in the evaluated data, so that e.g. a precomposed "é" matches "e" followed by a combining accent.
+func WithUnicodeNormalization() RuleOption {
+	return func(o *ruleOptions) {
+		if !o.caseFolding {
+			o.normalizeString = nfcString
+		}
+	}
+}
+
+// WithCaseFolding additionally applies Unicode case folding to the strings normalized by WithUnicodeNormalization,
+// making every string operator of the rule case-insensitive.
+func WithCaseFolding() RuleOption {
+	return func(o *ruleOptions) {
+		o.normalizeString = foldString
+		o.caseFolding = true
+	}
+}
+
+// WithClock sets the function returning the current time used by time relative operators like withinLast.
+// By default time.Now is used.
+func WithClock(now func() time.Time) RuleOption {
+	return func(o *ruleOptions) {
+		o.clock = now
+	}
 }
 
 // NewRule creates a new rule with given conditions and event handler.
@@ -17,39 +69,57 @@ type Rule struct {
 // Each condition map contains field-operator-value pairs.
 // For nested conditions, use ".$nested" as the operator and provide a map as the value.
 //
-// params: A map of parameters that can be used in the conditions.
+// params: A map of parameters that can be used in the conditions by referencing them as "${params.<name>}",
+// they are also passed on to the event handler.
 //
 // onMatch: An event handler function that will be called when the rule matches.
 //
+// opts: Optional settings applied to the whole rule, e.g. WithCaseFolding.
+//
 // Returns a pointer to a new Rule instance.
-func NewRule(conditions io.Reader, params map[string]interface{}, onMatch eventHandler) (*Rule, error) {
+func NewRule(conditions io.Reader, params map[string]interface{}, onMatch EventHandler, opts ...RuleOption) (*Rule, error) {
+
+	var options ruleOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 
 	var parsedConditions []map[string]interface{}
 
-	conditionBytes, err := io.ReadAll(conditions)
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(conditionBytes, &parsedConditions); err != nil {
+	if err := decodeJSON(conditions, &parsedConditions); err != nil {
 		return nil, err
 	}
 
-	processedConditions, err := parseJsonToContext(parsedConditions)
+	processedConditions, err := ruleParser{options: options, params: params}.parseJsonToContext(parsedConditions)
 	if err != nil {
 		return nil, err
 	}
+	id := options.id
+	if ruleID, ok := params["rule_id"]; ok && id == "" {
+		id = fmt.Sprint(ruleID)
+	}
 	return &Rule{
-		condition:  processedConditions,
-		onMatch:    onMatch,
-		extraParam: params,
+		id:            id,
+		condition:     processedConditions,
+		onMatch:       onMatch,
+		extraParam:    params,
+		branches:      compileConditions(processedConditions),
+		memoizeFields: hasRepeatedFields(processedConditions),
 	}, nil
 }
 
+// ID returns the ID of the rule, see WithID.
+func (r *Rule) ID() string {
+	return r.id
+}
+
 // IsMatch checks if the provided data meets the rule's conditions.
 //
-// The `data` is first normalized and merged with itself for evaluation. Note that when JSON is unmarshalled
-// into a map in Go, numeric fields like `{"a": 4}` may be represented as `float64` instead of `int`.
-// Be mindful of this when passing data parsed from JSON to ensure compatibility.
+// Dotted fields are looked up as if `data` was flattened and merged with itself, e.g. "a.b" resolving to all "b"
+// within "a", however only the fields referenced by the conditions are resolved. Numbers of any Go kind as well as
+// `json.Number` are compared by value, so a rule value of `4` matches `4.0` parsed from JSON. To compare
+// integers that can not be represented exactly as `float64`, decode the data using `json.Decoder.UseNumber`,
+// as Send does.
 //
 // Parameters:
 // - data: A map representing the input data to be checked.
@@ -57,34 +127,49 @@ func NewRule(conditions io.Reader, params map[string]interface{}, onMatch eventH
 // Returns:
 // - bool: true if the data matches the conditions, false otherwise.
 func (r *Rule) IsMatch(data map[string]interface{}) bool {
+	return r.evaluate(newDocument(data, r.memoizeFields))
+}
+
+// evaluate checks the rule's conditions against a document, which can be shared between multiple rules.
+func (r *Rule) evaluate(doc *document) bool {
+	return r.matchingBranch(doc) >= 0
+}
 
-	var normalizedJson = transformJSON(data, "")
-	normalizedJson = concatMaps(data, normalizedJson)
-	orMatches := []bool{}
-	for _, e := range r.condition {
+// matchingBranch returns the position of the first branch of the rule matching the document, or -1 if none does.
+func (r *Rule) matchingBranch(doc *document) int {
+	return firstMatch(r.branches, doc)
+}
 
-		andMatches := []bool{}
-		for _, eachContext := range e {
-			andMatches = append(andMatches, eachContext.Evaluate(normalizedJson))
+// hasRepeatedFields reports whether the same field of the normalized data is referenced more than once by the conditions.
+func hasRepeatedFields(conditions [][]constraint) bool {
+	seen := map[string]bool{}
+	repeated := false
+	for _, andConditions := range conditions {
+		for _, c := range andConditions {
+			flatFields(c, func(field string) {
+				repeated = repeated || seen[field]
+				seen[field] = true
+			})
 		}
-		orMatches = append(orMatches, allMatch(andMatches))
 	}
-	return anyMatch(orMatches)
-
+	return repeated
 }
 
+// Send parses data as JSON and, if it matches the rule's conditions, calls the event handler of the rule.
+// If the event handler fails, the returned error is a *HandlerError naming the rule and the failing handler.
 func (r *Rule) Send(data io.Reader) error {
-	var parsedData map[string]interface{}
+	return r.SendContext(context.Background(), data)
+}
 
-	dataBytes, err := io.ReadAll(data)
+// SendContext is like Send, passing ctx to the event handler.
+func (r *Rule) SendContext(ctx context.Context, data io.Reader) error {
+	parsedData, err := decodeJSONObject(data)
 	if err != nil {
 		return err
 	}
-	if err := json.Unmarshal(dataBytes, &parsedData); err != nil {
-		return err
-	}
-	if isMatch := r.IsMatch(parsedData); isMatch {
-		r.onMatch.call(parsedData, r.extraParam)
+	doc := newDocument(parsedData, r.memoizeFields)
+	if branch := r.matchingBranch(doc); branch >= 0 {
+		return handle(ctx, r.onMatch, r.newMatch(parsedData, branch))
 	}
 	return nil
 }
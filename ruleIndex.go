@@ -0,0 +1,171 @@
+package jsontology
+
+import (
+	"reflect"
+	"slices"
+)
+
+// indexedOperators are the operators whose constraints can be indexed, along with their built-in function.
+// A constraint is only indexed while its operator has not been replaced by RegisterNewOperator.
+var indexedOperators = map[Operator]OperatorFunc{
+	equals: isEquals,
+	in:     isInSet,
+}
+
+// ruleIndex selects the rules of a rule set which can match an event, without evaluating every rule.
+//
+// Rules testing a field for equality, i.e. using eq or in, are stored in a hash map of the field keyed by the tested
+// values, shared by all rules testing the same field. For an event, only the rules stored under the values of
+// the indexed fields, as well as the rules which could not be indexed, are candidates to be evaluated.
+type ruleIndex struct {
+	rules  []*Rule
+	fields []*indexedField
+	// unindexed holds the positions of the rules which have to be evaluated for every event
+	unindexed []int
+}
+
+// indexedField maps the values of a field to the positions of the rules that only match for that value.
+type indexedField struct {
+	field    string
+	resolver fieldResolver
+	rules    map[interface{}][]int
+}
+
+// indexTerm is an indexable constraint of a branch of a rule, matching only if the field holds one of keys.
+type indexTerm struct {
+	field    string
+	resolver fieldResolver
+	keys     []interface{}
+}
+
+// newRuleIndex builds the index of rules. Each OR branch of a rule is indexed by one of its AND-ed eq or in constraints,
+// preferring the fields tested by the most rules. A rule with a branch lacking such a constraint is not indexed.
+func newRuleIndex(rules []*Rule) *ruleIndex {
+	idx := &ruleIndex{rules: rules}
+
+	branchTerms := make([][][]indexTerm, len(rules))
+	fieldUsage := map[string]int{}
+	for i, r := range rules {
+		branchTerms[i] = make([][]indexTerm, len(r.condition))
+		fieldsOfRule := map[string]bool{}
+		for j, branch := range r.condition {
+			branchTerms[i][j] = indexTerms(branch, nil)
+			for _, term := range branchTerms[i][j] {
+				fieldsOfRule[term.field] = true
+			}
+		}
+		for field := range fieldsOfRule {
+			fieldUsage[field]++
+		}
+	}
+
+	fieldsByName := map[string]*indexedField{}
+	for i, r := range rules {
+		var chosen []indexTerm
+		for _, terms := range branchTerms[i] {
+			if len(terms) == 0 {
+				chosen = nil
+				break
+			}
+			chosen = append(chosen, slices.MinFunc(terms, func(a, b indexTerm) int {
+				if fieldUsage[a.field] != fieldUsage[b.field] {
+					return fieldUsage[b.field] - fieldUsage[a.field]
+				}
+				return len(a.keys) - len(b.keys)
+			}))
+		}
+		if len(chosen) == 0 || len(chosen) != len(r.condition) {
+			idx.unindexed = append(idx.unindexed, i)
+			continue
+		}
+
+		for _, term := range chosen {
+			f, ok := fieldsByName[term.field]
+			if !ok {
+				f = &indexedField{field: term.field, resolver: term.resolver, rules: map[interface{}][]int{}}
+				fieldsByName[term.field] = f
+				idx.fields = append(idx.fields, f)
+			}
+			for _, key := range term.keys {
+				// a rule may be stored under the same key by several branches
+				if positions := f.rules[key]; len(positions) == 0 || positions[len(positions)-1] != i {
+					f.rules[key] = append(positions, i)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// indexTerms appends the indexable constraints of the AND-ed conditions to terms, including those of $and groups.
+func indexTerms(conditions []constraint, terms []indexTerm) []indexTerm {
+	for _, c := range conditions {
+		switch c := c.(type) {
+		case andGroup:
+			terms = indexTerms(c.conditions, terms)
+		case criteria:
+			if term, ok := asIndexTerm(c); ok {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// asIndexTerm returns the index term of an eq or in constraint comparing the field with literal values.
+// Strings are not indexed if the rule normalizes them, as the normalized field value is not known to the index.
+func asIndexTerm(c criteria) (indexTerm, bool) {
+	builtin, ok := indexedOperators[c.operator]
+	if !ok || c.reference != nil || c.normalizeString != nil || !isSameFunc(operatorFuncMapping[c.operator], builtin) {
+		return indexTerm{}, false
+	}
+
+	term := indexTerm{field: c.field, resolver: c.resolver}
+	switch c.operator {
+	case equals:
+		// null and compound values are never indexed, eq does not match them the way a lookup would
+		key, ok := setKey(c.value)
+		if !ok || key == nil {
+			return indexTerm{}, false
+		}
+		term.keys = []interface{}{key}
+	case in:
+		for key := range c.value.(valueSet) {
+			term.keys = append(term.keys, key)
+		}
+	}
+	return term, true
+}
+
+func isSameFunc(a, b OperatorFunc) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// candidates returns the positions, in ascending order, of the rules which can match the document.
+func (idx *ruleIndex) candidates(doc *document) []int {
+	positions := append([]int(nil), idx.unindexed...)
+	for _, f := range idx.fields {
+		value, ok := f.resolver.resolve(doc)
+		if !ok {
+			continue
+		}
+		positions = f.appendCandidates(value, positions)
+	}
+	slices.Sort(positions)
+	return slices.Compact(positions)
+}
+
+// appendCandidates appends the rules stored under value, or under any element of value if it is an array,
+// as eq and in also match if any element of an array field is equal.
+func (f *indexedField) appendCandidates(value interface{}, positions []int) []int {
+	if elements, ok := value.([]interface{}); ok {
+		for _, element := range elements {
+			positions = f.appendCandidates(element, positions)
+		}
+		return positions
+	}
+	if key, ok := setKey(value); ok {
+		positions = append(positions, f.rules[key]...)
+	}
+	return positions
+}
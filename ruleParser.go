@@ -1,50 +1,444 @@
 package jsontology
 
 import (
-	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 )
 
-func parseJsonToContext(data []map[string]interface{}) ([][]constraint, error) {
+const (
+	andGroupKey string = "$and"
+	orGroupKey  string = "$or"
+	notGroupKey string = "$not"
+
+	fieldReferenceKey string = "$field"
+
+	jsonPathKey       string = "$path"
+	jsonPathSelectKey string = "select"
+
+	countConditionKey string = "where"
+)
+
+// countOperators are the operators a $count constraint can compare the number of matching elements with.
+var countOperators = []Operator{equals, notEquals, greaterThan, greaterEqual, lessThan, lessEqual, between}
+
+// paramPlaceholder matches references to rule parameters within condition values, e.g. ${params.watchlist}.
+var paramPlaceholder = regexp.MustCompile(`\$\{params\.([^}]+)\}`)
+
+// ruleParser parses rule conditions into constraints, applying the rule level options and parameters.
+type ruleParser struct {
+	options ruleOptions
+	params  map[string]interface{}
+}
+
+func (p ruleParser) parseJsonToContext(data []map[string]interface{}) ([][]constraint, error) {
 
 	var returnContext [][]constraint
 	for _, eachData := range data {
 		// prepare internal constraint var which will hold internal array in 2d array on returnContext
-		var internalContext []constraint
-		for key, value := range eachData {
-			splittedString := strings.Split(key, ".$") // separator
-			field, operator := splittedString[0], Operator(splittedString[1])
-
-			if operator == nested {
-				formattedValue, ok := value.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("parsing error, value for nested operator is not map[string]interface{}")
-				}
-				mapFormattedValue := []map[string]interface{}{formattedValue}
-				nestedContext, err := parseJsonToContext(mapFormattedValue)
-				if err != nil {
-					return nil, err
-				}
-				internalContext = append(internalContext, nestedCriteria{
-					path:       field,
-					conditions: nestedContext[0],
-				})
-			} else {
-				if transformer, ok := operatorTypeHandlerMapping[operator]; ok {
-					transformedValue, err := transformer(value)
-					if err != nil {
-						return nil, err
-					}
-					value = transformedValue
-				}
-				internalContext = append(internalContext, criteria{
-					field:    field,
-					operator: operator,
-					value:    value,
-				})
-			}
+		internalContext, err := p.parseConditionMap(eachData)
+		if err != nil {
+			return nil, err
 		}
 		returnContext = append(returnContext, internalContext)
 	}
 	return returnContext, nil
 }
+
+// parseConditionMap parses a single condition map, all constraints of which are AND-ed together.
+func (p ruleParser) parseConditionMap(data map[string]interface{}) ([]constraint, error) {
+
+	// keys are parsed in order, so that constraints of the same cost are evaluated, and their values reported, in a stable order
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var internalContext []constraint
+	for _, key := range keys {
+		value := data[key]
+		switch key {
+		case andGroupKey, orGroupKey:
+			groupContext, err := p.parseConditionList(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if key == andGroupKey {
+				internalContext = append(internalContext, andGroup{conditions: groupContext})
+			} else {
+				internalContext = append(internalContext, orGroup{conditions: groupContext})
+			}
+			continue
+
+		case notGroupKey:
+			formattedValue, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("parsing error, value for %s is not map[string]interface{}", key)
+			}
+			notContext, err := p.parseConditionMap(formattedValue)
+			if err != nil {
+				return nil, err
+			}
+			internalContext = append(internalContext, notGroup{condition: andGroup{conditions: notContext}})
+			continue
+
+		case jsonPathKey:
+			pathContext, err := p.parseJSONPathConditions(value)
+			if err != nil {
+				return nil, err
+			}
+			internalContext = append(internalContext, pathContext...)
+			continue
+		}
+
+		field, operator, ok := splitOperator(key)
+		if !ok {
+			return nil, fmt.Errorf("parsing error, key %s does not specify an operator", key)
+		}
+		c, err := p.parseCondition(field, operator, value)
+		if err != nil {
+			return nil, err
+		}
+		internalContext = append(internalContext, c)
+	}
+	return internalContext, nil
+}
+
+// parseCondition parses the condition on a single field, i.e. the value given to a "<field>.$<operator>" key.
+func (p ruleParser) parseCondition(field string, operator Operator, value interface{}) (constraint, error) {
+	resolver, err := parseField(field)
+	if err != nil {
+		return nil, err
+	}
+
+	if operator == nested || operator == every || operator == none {
+		formattedValue, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parsing error, value for %s operator is not map[string]interface{}", operator)
+		}
+		nestedContext, err := p.parseConditionMap(formattedValue)
+		if err != nil {
+			return nil, err
+		}
+		n := nestedCriteria{
+			path:       field,
+			resolver:   resolver,
+			conditions: nestedContext,
+		}
+		switch operator {
+		case every:
+			return everyCriteria(n), nil
+		case none:
+			return noneCriteria(n), nil
+		}
+		return n, nil
+	}
+
+	if operator == count {
+		return p.parseCount(nestedCriteria{path: field, resolver: resolver}, value)
+	}
+
+	// an operator can be prefixed by a quantifier, e.g. "all.eq", to apply it to the elements of an array
+	var q quantifier
+	if prefix, quantifiedOperator, ok := strings.Cut(string(operator), "."); ok && isQuantifier(quantifier(prefix)) {
+		q, operator = quantifier(prefix), Operator(quantifiedOperator)
+	}
+	if _, ok := operatorFuncMapping[operator]; !ok {
+		return nil, fmt.Errorf("parsing error, unknown operator %s", operator)
+	}
+
+	value, err = p.substituteParams(value)
+	if err != nil {
+		return nil, err
+	}
+	c := criteria{
+		field:    field,
+		resolver: resolver,
+		operator: operator,
+	}
+	if normalizedOperators[operator] {
+		c.normalizeString = p.options.normalizeString
+	}
+	c.clock = p.options.clock
+	c.rawValue = value
+	if reference, ok := asFieldReference(value); ok {
+		// the referenced value is only known, and passed through the type handler, at evaluation
+		if c.reference, err = parseField(reference); err != nil {
+			return nil, err
+		}
+	} else {
+		if c.normalizeString != nil {
+			value = p.normalizeRuleValue(operator, value)
+		}
+		if transformer, ok := operatorTypeHandlerMapping[operator]; ok {
+			transformedValue, err := transformer(value)
+			if err != nil {
+				return nil, err
+			}
+			value = transformedValue
+		}
+		c.value = value
+	}
+	if q != "" {
+		return quantifiedCriteria{criteria: c, quantifier: q}, nil
+	}
+	return c, nil
+}
+
+// parseJSONPathConditions parses the value of a $path key, i.e. a condition or list of conditions selecting their
+// field by a JSONPath expression, e.g. {"select": "$.items[?(@.price > 100)].sku", "in": ["a", "b"]}. Unlike a
+// "<field>.$<operator>" key, the expression can contain any character, including ".$".
+func (p ruleParser) parseJSONPathConditions(value interface{}) ([]constraint, error) {
+	listValue, ok := value.([]interface{})
+	if !ok {
+		listValue = []interface{}{value}
+	}
+
+	var pathContext []constraint
+	for _, eachValue := range listValue {
+		formattedValue, ok := eachValue.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parsing error, value for %s is not map[string]interface{}", jsonPathKey)
+		}
+		expr, ok := formattedValue[jsonPathSelectKey].(string)
+		if !ok || len(formattedValue) != 2 {
+			return nil, fmt.Errorf("parsing error, %s requires a %s expression and a single operator", jsonPathKey, jsonPathSelectKey)
+		}
+		if !isJSONPath(expr) {
+			return nil, fmt.Errorf("parsing error, %s of %s is not a JSONPath expression starting with $", jsonPathSelectKey, jsonPathKey)
+		}
+		for key, conditionValue := range formattedValue {
+			if key == jsonPathSelectKey {
+				continue
+			}
+			c, err := p.parseCondition(expr, Operator(key), conditionValue)
+			if err != nil {
+				return nil, err
+			}
+			pathContext = append(pathContext, c)
+		}
+	}
+	return pathContext, nil
+}
+
+// parseCount parses the value of a $count constraint, e.g. {"where": {"price.$gt": 100}, "gte": 2},
+// holding the optional conditions elements have to match to be counted and a single comparison of the count.
+func (p ruleParser) parseCount(n nestedCriteria, value interface{}) (constraint, error) {
+	formattedValue, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parsing error, value for %s operator is not map[string]interface{}", count)
+	}
+	c := countCriteria{nestedCriteria: n}
+
+	for key, eachValue := range formattedValue {
+		if key == countConditionKey {
+			formattedConditions, ok := eachValue.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("parsing error, %s of %s operator is not map[string]interface{}", countConditionKey, count)
+			}
+			nestedContext, err := p.parseConditionMap(formattedConditions)
+			if err != nil {
+				return nil, err
+			}
+			c.conditions = nestedContext
+			continue
+		}
+
+		operator := Operator(key)
+		if !slices.Contains(countOperators, operator) || c.operator != "" {
+			return nil, fmt.Errorf("parsing error, %s operator requires a single comparison out of %v", count, countOperators)
+		}
+		comparisonValue, err := p.substituteParams(eachValue)
+		if err != nil {
+			return nil, err
+		}
+		// counts are numbers, so the operators without type handler have to be compared with a number as well
+		handler := operatorTypeHandlerMapping[operator]
+		if handler == nil {
+			handler = isNumber
+		}
+		if c.value, err = handler(comparisonValue); err != nil {
+			return nil, err
+		}
+		c.operator, c.rawValue = operator, comparisonValue
+	}
+	if c.operator == "" {
+		return nil, fmt.Errorf("parsing error, %s operator requires a single comparison out of %v", count, countOperators)
+	}
+	return c, nil
+}
+
+// parseConditionList parses the list of condition maps given to a logical group,
+// every condition map in the list is wrapped in an andGroup.
+func (p ruleParser) parseConditionList(key string, value interface{}) ([]constraint, error) {
+
+	listValue, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parsing error, value for %s is not []interface{}", key)
+	}
+	var groupContext []constraint
+	for _, eachValue := range listValue {
+		formattedValue, ok := eachValue.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parsing error, element of %s is not map[string]interface{}", key)
+		}
+		conditions, err := p.parseConditionMap(formattedValue)
+		if err != nil {
+			return nil, err
+		}
+		groupContext = append(groupContext, andGroup{conditions: conditions})
+	}
+	return groupContext, nil
+}
+
+// normalizeRuleValue applies the string normalization of the rule to a rule value. Regular expressions are brought
+// to NFC form, and with case folding only their literals are folded, as folding the whole expression could change
+// its meaning (e.g. \S to \s).
+func (p ruleParser) normalizeRuleValue(operator Operator, value interface{}) interface{} {
+	regStr, ok := value.(string)
+	if !ok || (operator != regexMatch && operator != notRegexMatch) {
+		return normalizeStrings(value, p.options.normalizeString)
+	}
+	if p.options.caseFolding {
+		return foldRegex(nfcString(regStr))
+	}
+	return nfcString(regStr)
+}
+
+// splitOperator splits a condition key into its field and operator at the first ".$" which is not within a quoted key
+// or brackets, the latter holding e.g. the filter expressions of a JSONPath.
+func splitOperator(key string) (string, Operator, bool) {
+	var quote byte
+	brackets := 0
+	for i := 0; i < len(key)-1; i++ {
+		switch {
+		case quote != 0 && key[i] == '\\':
+			i++
+		case quote != 0:
+			if key[i] == quote {
+				quote = 0
+			}
+		// single quotes only enclose strings within brackets, elsewhere they are part of the key
+		case key[i] == '"' || (key[i] == '\'' && brackets > 0):
+			quote = key[i]
+		case key[i] == '[':
+			brackets++
+		case key[i] == ']' && brackets > 0:
+			brackets--
+		case brackets == 0 && key[i] == '.' && key[i+1] == '$':
+			return key[:i], Operator(key[i+2:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseField returns the resolver looking up field, which is either a JSONPath expression, a path expression
+// or a dotted name of the normalized data, in which the path syntax can be escaped.
+func parseField(field string) (fieldResolver, error) {
+	if isJSONPath(field) {
+		return parseJSONPath(field)
+	}
+	if isPathExpression(field) {
+		return parseFieldPath(field)
+	}
+	return flatField(unescapeFieldName(field)), nil
+}
+
+// asFieldReference returns the referenced field if value is a reference to another field, i.e. {"$field": "a.b"}.
+func asFieldReference(value interface{}) (string, bool) {
+	mapValue, ok := value.(map[string]interface{})
+	if !ok || len(mapValue) != 1 {
+		return "", false
+	}
+	reference, ok := mapValue[fieldReferenceKey].(string)
+	return reference, ok
+}
+
+// substituteParams replaces references to rule parameters within value. A string consisting of a single reference
+// is replaced by the parameter value itself, keeping its type, while references embedded in a longer string are
+// replaced by the parameter formatted as string. Nested parameters can be referenced using dots, e.g. ${params.a.b}.
+func (p ruleParser) substituteParams(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := paramPlaceholder.FindStringSubmatchIndex(v); match != nil && match[0] == 0 && match[1] == len(v) {
+			return p.param(v[match[2]:match[3]])
+		}
+		var err error
+		substituted := paramPlaceholder.ReplaceAllStringFunc(v, func(placeholder string) string {
+			param, paramErr := p.param(paramPlaceholder.FindStringSubmatch(placeholder)[1])
+			if paramErr != nil {
+				err = paramErr
+			}
+			return fmt.Sprint(param)
+		})
+		return substituted, err
+
+	case []interface{}:
+		substituted := make([]interface{}, len(v))
+		for i, eachValue := range v {
+			substitutedValue, err := p.substituteParams(eachValue)
+			if err != nil {
+				return nil, err
+			}
+			substituted[i] = substitutedValue
+		}
+		return substituted, nil
+
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(v))
+		for key, eachValue := range v {
+			substitutedValue, err := p.substituteParams(eachValue)
+			if err != nil {
+				return nil, err
+			}
+			substituted[key] = substitutedValue
+		}
+		return substituted, nil
+	}
+	return value, nil
+}
+
+// param looks up a rule parameter by its dotted name. Slices and maps given from Go are converted
+// to []interface{} and map[string]interface{}, the same types a parsed JSON condition would hold.
+func (p ruleParser) param(name string) (interface{}, error) {
+	var value interface{} = p.params
+	for _, key := range strings.Split(name, ".") {
+		paramMap, ok := asJsonValue(value).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parsing error, parameter %s is not defined", name)
+		}
+		if value, ok = paramMap[key]; !ok {
+			return nil, fmt.Errorf("parsing error, parameter %s is not defined", name)
+		}
+	}
+	return asJsonValue(value), nil
+}
+
+// asJsonValue converts slices and maps with string keys of any element type to []interface{} and map[string]interface{}.
+func asJsonValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if _, ok := value.([]interface{}); ok || v.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		converted := make([]interface{}, v.Len())
+		for i := range converted {
+			converted[i] = asJsonValue(v.Index(i).Interface())
+		}
+		return converted
+	case reflect.Map:
+		if _, ok := value.(map[string]interface{}); ok || v.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		converted := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			converted[key.String()] = asJsonValue(v.MapIndex(key).Interface())
+		}
+		return converted
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package jsontology
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// RuleSet holds a collection of rules that are evaluated together.
+//
+// Unlike calling IsMatch on every rule, a RuleSet resolves each field of an event only once,
+// sharing the resolved fields between all of its rules. Rules testing fields with eq or in are
+// indexed by the tested values, so that only the rules which can match an event are evaluated.
+// A RuleSet is safe for concurrent use.
+type RuleSet struct {
+	mu    sync.RWMutex
+	rules []*Rule
+	// index is built on the first match after the rules have changed
+	index *ruleIndex
+}
+
+// NewRuleSet creates a new rule set holding the given rules.
+func NewRuleSet(rules ...*Rule) *RuleSet {
+	rs := &RuleSet{}
+	rs.Add(rules...)
+	return rs
+}
+
+// Add appends the given rules to the rule set.
+func (rs *RuleSet) Add(rules ...*Rule) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.rules = append(rs.rules, rules...)
+	rs.index = nil
+}
+
+// Remove removes the given rule from the rule set.
+//
+// Returns true if the rule was part of the rule set, false otherwise.
+func (rs *RuleSet) Remove(rule *Rule) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i, r := range rs.rules {
+		if r == rule {
+			rs.rules = append(rs.rules[:i:i], rs.rules[i+1:]...)
+			rs.index = nil
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns a copy of the rules currently held by the rule set.
+func (rs *RuleSet) Rules() []*Rule {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return append([]*Rule(nil), rs.rules...)
+}
+
+// Match returns every rule in the rule set whose conditions are met by data.
+//
+// Fields are resolved once and shared between all rules, see Rule.IsMatch for details.
+func (rs *RuleSet) Match(data map[string]interface{}) []*Rule {
+	return rs.match(newDocument(data, true))
+}
+
+// match returns every rule in the rule set whose conditions are met by the document.
+func (rs *RuleSet) match(doc *document) []*Rule {
+	var matches []*Rule
+	rs.eachMatch(doc, func(r *Rule, branch int) {
+		matches = append(matches, r)
+	})
+	return matches
+}
+
+// eachMatch calls fn for every rule whose conditions are met by the document, along with its first matching branch.
+func (rs *RuleSet) eachMatch(doc *document, fn func(r *Rule, branch int)) {
+	idx := rs.ruleIndex()
+	for _, position := range idx.candidates(doc) {
+		r := idx.rules[position]
+		if branch := r.matchingBranch(doc); branch >= 0 {
+			fn(r, branch)
+		}
+	}
+}
+
+// ruleIndex returns the index of the current rules, building it if the rules have changed.
+func (rs *RuleSet) ruleIndex() *ruleIndex {
+	rs.mu.RLock()
+	idx := rs.index
+	rs.mu.RUnlock()
+	if idx != nil {
+		return idx
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.index == nil {
+		rs.index = newRuleIndex(append([]*Rule(nil), rs.rules...))
+	}
+	return rs.index
+}
+
+// Send parses data as JSON and calls the event handler of every matching rule.
+// The event handlers of all matching rules are called even if one of them fails, the *HandlerError of each
+// failing rule being joined.
+func (rs *RuleSet) Send(data io.Reader) error {
+	return rs.SendContext(context.Background(), data)
+}
+
+// SendContext is like Send, passing ctx to the event handlers.
+func (rs *RuleSet) SendContext(ctx context.Context, data io.Reader) error {
+	parsedData, err := decodeJSONObject(data)
+	if err != nil {
+		return err
+	}
+	doc := newDocument(parsedData, true)
+	var errs []error
+	rs.eachMatch(doc, func(r *Rule, branch int) {
+		if err := handle(ctx, r.onMatch, r.newMatch(parsedData, branch)); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
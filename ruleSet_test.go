@@ -0,0 +1,215 @@
+package jsontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRuleSetMatch(t *testing.T) {
+	table := []struct {
+		name       string
+		conditions []string
+		data       string
+		expected   []int
+	}{
+		{
+			name:       "all rules match",
+			conditions: []string{`[{"a.a.$eq":1}]`, `[{"c.$eq":"1"}]`},
+			data:       `{"a":[{"a":1},{"a":2}],"c":"1"}`,
+			expected:   []int{0, 1},
+		},
+		{
+			name:       "some rules match",
+			conditions: []string{`[{"a.a.$eq":3}]`, `[{"c.$eq":"1"}]`, `[{"a.$nested":{"a.$eq":2}}]`},
+			data:       `{"a":[{"a":1},{"a":2}],"c":"1"}`,
+			expected:   []int{1, 2},
+		},
+		{
+			name:       "no rule matches",
+			conditions: []string{`[{"a.a.$eq":3}]`, `[{"c.$eq":"2"}]`},
+			data:       `{"a":[{"a":1},{"a":2}],"c":"1"}`,
+			expected:   []int{},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			rules := []*Rule{}
+			for _, eachCondition := range tt.conditions {
+				r, err := NewRule(strings.NewReader(eachCondition), map[string]interface{}{}, &LogEventHandler{})
+				if err != nil {
+					t.Fatal("unable to parse to rule, received error : ", err)
+				}
+				rules = append(rules, r)
+			}
+
+			got := NewRuleSet(rules...).Match(data)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Match() returned %d rules, want %d", len(got), len(tt.expected))
+			}
+			for i, index := range tt.expected {
+				if got[i] != rules[index] {
+					t.Errorf("Match()[%d] is not rule %d", i, index)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleSetAddRemove(t *testing.T) {
+	first, _ := NewRule(strings.NewReader(`[{"c.$eq":"1"}]`), map[string]interface{}{}, &LogEventHandler{})
+	second, _ := NewRule(strings.NewReader(`[{"c.$eq":"1"}]`), map[string]interface{}{}, &LogEventHandler{})
+	data := map[string]interface{}{"c": "1"}
+
+	rs := NewRuleSet(first)
+	rs.Add(second)
+	if got := len(rs.Match(data)); got != 2 {
+		t.Fatalf("Match() returned %d rules, want 2", got)
+	}
+	if !rs.Remove(first) {
+		t.Fatal("Remove() = false, want true")
+	}
+	if rs.Remove(first) {
+		t.Fatal("Remove() of already removed rule = true, want false")
+	}
+	if got := rs.Match(data); len(got) != 1 || got[0] != second {
+		t.Fatalf("Match() after Remove() = %v, want only second rule", got)
+	}
+}
+
+func TestRuleSetSend(t *testing.T) {
+	matched, unmatched := &eventHandlerMock{}, &eventHandlerMock{}
+	matched.On("Handle").Times(1)
+
+	first, _ := NewRule(strings.NewReader(`[{"c.$eq":"1"}]`), map[string]interface{}{}, matched)
+	second, _ := NewRule(strings.NewReader(`[{"c.$eq":"2"}]`), map[string]interface{}{}, unmatched)
+
+	if err := NewRuleSet(first, second).Send(strings.NewReader(`{"c":"1"}`)); err != nil {
+		t.Fatal("unable to send event, received error", err)
+	}
+	matched.AssertExpectations(t)
+	unmatched.AssertNotCalled(t, "Handle")
+}
+
+func TestRuleSetIndex(t *testing.T) {
+	conditions := []string{
+		`[{"type.$eq":"login"}]`,
+		`[{"type.$eq":"login","user.$in":["alice","bob"]}]`,
+		`[{"type.$in":["logout","login"],"severity.$gt":3}]`,
+		`[{"type.$eq":"logout"},{"user.$eq":"alice"}]`,
+		`[{"type.$eq":"logout"},{"severity.$gt":3}]`,
+		`[{"$and":[{"code.$eq":200}]}]`,
+		`[{"code.$in":[404,null]}]`,
+		`[{"code.$eq":null}]`,
+		`[{"tags.$eq":"prod"}]`,
+		`[{"items.sku.$eq":"b"}]`,
+		`[{"type.$eq":{"$field":"expected"}}]`,
+		`[{"flag.$eq":true}]`,
+		`[{"type.$ieq":"LOGIN"}]`,
+	}
+	events := []string{
+		`{"type":"login","user":"alice","severity":5}`,
+		`{"type":"logout","user":"alice","severity":1}`,
+		`{"type":"other","user":"carol","severity":4}`,
+		`{"code":200}`,
+		`{"code":200.0,"type":["login","x"]}`,
+		`{"code":null}`,
+		`{"code":404}`,
+		`{"tags":["dev",["prod"]]}`,
+		`{"items":[{"sku":"a"},{"sku":"b"}]}`,
+		`{"type":"x","expected":"x"}`,
+		`{"flag":true}`,
+		`{}`,
+	}
+
+	var rules []*Rule
+	for _, condition := range conditions {
+		r, err := NewRule(strings.NewReader(condition), nil, nil)
+		if err != nil {
+			t.Fatal("unable to parse to rule, received error : ", err)
+		}
+		rules = append(rules, r)
+	}
+	rs := NewRuleSet(rules...)
+
+	// the index must select the same rules as evaluating every rule on its own
+	for _, event := range events {
+		t.Run(event, func(t *testing.T) {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(event), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			var expected []*Rule
+			for _, r := range rules {
+				if r.IsMatch(data) {
+					expected = append(expected, r)
+				}
+			}
+			if got := rs.Match(data); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Match() = %v; want %v", got, expected)
+			}
+		})
+	}
+
+	// rules with a branch without eq or in, comparing with null or a field, or normalizing strings are not indexed
+	idx := rs.ruleIndex()
+	if got, want := idx.unindexed, []int{4, 7, 10, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unindexed rules = %v; want %v", got, want)
+	}
+	if got, want := idx.candidates(newDocument(map[string]interface{}{"type": "logout"}, true)), []int{2, 3, 4, 7, 10, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates() = %v; want %v", got, want)
+	}
+}
+
+// syntheticRuleSet creates n rules each testing a different event type and a common threshold.
+func syntheticRuleSet(b *testing.B, n int) *RuleSet {
+	rs := NewRuleSet()
+	for i := 0; i < n; i++ {
+		condition := fmt.Sprintf(`[{"event.type.$eq":"type-%d","severity.$gt":%d},{"event.type.$in":["group-%d"],"user.$rgx":"^svc-"}]`, i, i%10, i%50)
+		r, err := NewRule(strings.NewReader(condition), nil, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		rs.Add(r)
+	}
+	return rs
+}
+
+func BenchmarkRuleSetMatch(b *testing.B) {
+	rs := syntheticRuleSet(b, 5000)
+	data := map[string]interface{}{"event": map[string]interface{}{"type": "type-42"}, "severity": 5, "user": "alice"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(rs.Match(data)) != 1 {
+			b.Fatal("expected a single match")
+		}
+	}
+}
+
+// BenchmarkRuleSetMatchWithoutIndex evaluates every rule of the same rule set, as done before rules were indexed.
+func BenchmarkRuleSetMatchWithoutIndex(b *testing.B) {
+	rs := syntheticRuleSet(b, 5000)
+	data := map[string]interface{}{"event": map[string]interface{}{"type": "type-42"}, "severity": 5, "user": "alice"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc := newDocument(data, true)
+		var matches []*Rule
+		for _, r := range rs.Rules() {
+			if r.evaluate(doc) {
+				matches = append(matches, r)
+			}
+		}
+		if len(matches) != 1 {
+			b.Fatal("expected a single match")
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRule(t *testing.T) {
@@ -53,6 +54,276 @@ func TestRule(t *testing.T) {
 			data:      `{"a":[{"a":2},{"a":2}],"g":"h"}`,
 			expected:  true,
 		},
+		{
+			name:      "exists on nested null",
+			condition: `[{"a.b.$exists":true,"a.b.$isNull":true,"a.c.$notExists":true}]`,
+			data:      `{"a":{"b":null}}`,
+			expected:  true,
+		},
+		{
+			name:      "type check",
+			condition: `[{"a.$isType":"array","b.$isType":"object","b.c.$isType":"string"}]`,
+			data:      `{"a":[1,2],"b":{"c":"d"}}`,
+			expected:  true,
+		},
+		{
+			name:      "set membership",
+			condition: `[{"user.$in":["alice","bob"],"tags.$containsAll":["x","y"],"a.b.$nin":[1,2]}]`,
+			data:      `{"user":"bob","tags":["y","z","x"],"a":{"b":3}}`,
+			expected:  true,
+		},
+		{
+			name:      "string operators do not match numbers",
+			condition: `[{"a.$sw":12}, {"b.$rgx":"^1"}]`,
+			data:      `{"a":"123","b":123}`,
+			expected:  false,
+		},
+		{
+			name:      "connection from public address",
+			condition: `[{"src.$isPublicIP":true,"dst.$isPrivateIP":true,"dst.$ipVersion":4}]`,
+			data:      `{"src":"203.0.113.7","dst":"10.0.0.5"}`,
+			expected:  true,
+		},
+		{
+			name:      "field reference",
+			condition: `[{"src.ip.$eq":{"$field":"dst.ip"},"bytes_out.$gt":{"$field":"bytes_in"}}]`,
+			data:      `{"src":{"ip":"10.0.0.1"},"dst":{"ip":"10.0.0.1"},"bytes_out":5000,"bytes_in":20}`,
+			expected:  true,
+		},
+		{
+			name:      "field reference no match",
+			condition: `[{"src.ip.$eq":{"$field":"dst.ip"}}]`,
+			data:      `{"src":{"ip":"10.0.0.1"},"dst":{"ip":"10.0.0.2"}}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference through type handler",
+			condition: `[{"src.$ipInRange":{"$field":"allowed"}}]`,
+			data:      `{"src":"10.0.0.1","allowed":"10.0.0.0/8"}`,
+			expected:  true,
+		},
+		{
+			name:      "field reference to invalid value",
+			condition: `[{"a.$gt":{"$field":"b"}}]`,
+			data:      `{"a":1,"b":"x"}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference to absent field",
+			condition: `[{"a.$neq":{"$field":"b"}}]`,
+			data:      `{"a":1}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference to longer array",
+			condition: `[{"a.$eq":{"$field":"b"}}]`,
+			data:      `{"a":[1],"b":[1,2]}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference to shorter array",
+			condition: `[{"a.$eq":{"$field":"b"}}]`,
+			data:      `{"a":[1,2],"b":[1]}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference to equal array",
+			condition: `[{"a.$eq":{"$field":"b"}}]`,
+			data:      `{"a":[1,2],"b":[1,2]}`,
+			expected:  true,
+		},
+		{
+			name:      "field reference to larger object",
+			condition: `[{"a.$eq":{"$field":"b"}}]`,
+			data:      `{"a":{"x":1},"b":{"x":1,"y":2}}`,
+			expected:  false,
+		},
+		{
+			name:      "field reference inside nested",
+			condition: `[{"items.$nested":{"price.$gt":{"$field":"limit"}}}]`,
+			data:      `{"items":[{"price":5,"limit":10},{"price":20,"limit":10}]}`,
+			expected:  true,
+		},
+		{
+			name:      "all elements quantifier",
+			condition: `[{"tags.$all.sw":"prod-","ports.$all.lt":1024}]`,
+			data:      `{"tags":["prod-web","prod-db"],"ports":[22,443]}`,
+			expected:  true,
+		},
+		{
+			name:      "all elements quantifier no match",
+			condition: `[{"tags.$all.eq":"prod"}]`,
+			data:      `{"tags":["prod","dev"]}`,
+			expected:  false,
+		},
+		{
+			name:      "none quantifier",
+			condition: `[{"a.ports.$none.in":[23,3389]}]`,
+			data:      `{"a":{"ports":[22,443]}}`,
+			expected:  true,
+		},
+		{
+			name:      "none quantifier on absent field",
+			condition: `[{"tags.$none.eq":"debug"}]`,
+			data:      `{}`,
+			expected:  true,
+		},
+		{
+			name:      "any quantifier on scalar",
+			condition: `[{"tag.$any.eq":"prod"}]`,
+			data:      `{"tag":"prod"}`,
+			expected:  true,
+		},
+		{
+			name:      "every nested",
+			condition: `[{"items.$every":{"price.$gt":100,"currency.$eq":"EUR"}}]`,
+			data:      `{"items":[{"price":150,"currency":"EUR"},{"price":200,"currency":"EUR"}]}`,
+			expected:  true,
+		},
+		{
+			name:      "every nested no match",
+			condition: `[{"items.$every":{"price.$gt":100}}]`,
+			data:      `{"items":[{"price":150},{"price":50}]}`,
+			expected:  false,
+		},
+		{
+			name:      "none nested",
+			condition: `[{"items.$none":{"price.$gt":100}}]`,
+			data:      `{"items":[{"price":10},{"price":50}]}`,
+			expected:  true,
+		},
+		{
+			name:      "none nested no match",
+			condition: `[{"items.$none":{"price.$gt":100}}]`,
+			data:      `{"items":[{"price":10},{"price":500}]}`,
+			expected:  false,
+		},
+		{
+			name:      "length of array",
+			condition: `[{"recipients.$lenGt":5,"subject.$lenLt":10}]`,
+			data:      `{"recipients":["a","b","c","d","e","f"],"subject":"hi"}`,
+			expected:  true,
+		},
+		{
+			name:      "count matching elements",
+			condition: `[{"items.$count":{"where":{"price.$gt":100},"gte":2}}]`,
+			data:      `{"items":[{"price":150},{"price":50},{"price":200}]}`,
+			expected:  true,
+		},
+		{
+			name:      "count matching elements below threshold",
+			condition: `[{"items.$count":{"where":{"price.$gt":100},"gte":2}}]`,
+			data:      `{"items":[{"price":150},{"price":50}]}`,
+			expected:  false,
+		},
+		{
+			name:      "count all elements",
+			condition: `[{"tags.$count":{"between":[2,3]}}]`,
+			data:      `{"tags":["a","b","c"]}`,
+			expected:  true,
+		},
+		{
+			name:      "count on absent field",
+			condition: `[{"items.$count":{"where":{"price.$gt":100},"eq":0}}]`,
+			data:      `{}`,
+			expected:  true,
+		},
+		{
+			name:      "positional path",
+			condition: `[{"a[0].b.$eq":1,"a[-1].b.$eq":2}]`,
+			data:      `{"a":[{"b":1},{"b":3},{"b":2}]}`,
+			expected:  true,
+		},
+		{
+			name:      "positional path no match",
+			condition: `[{"a[0].b.$eq":2}]`,
+			data:      `{"a":[{"b":1},{"b":2}]}`,
+			expected:  false,
+		},
+		{
+			name:      "wildcard path",
+			condition: `[{"services.*.status.$all.eq":"up","a[*].b.$gt":1}]`,
+			data:      `{"services":{"web":{"status":"up"},"db":{"status":"up"}},"a":[{"b":1},{"b":2}]}`,
+			expected:  true,
+		},
+		{
+			name:      "quoted key path",
+			condition: `[{"headers.\"x.forwarded.for\".$ipInRange":"10.0.0.0/8"}]`,
+			data:      `{"headers":{"x.forwarded.for":"10.1.1.1"}}`,
+			expected:  true,
+		},
+		{
+			name:      "escaped key containing path syntax",
+			condition: `[{"labels.app\\[web].$eq":"up","count\\*.$gt":1,"tags\\[0].$eq":{"$field":"labels.app\\[web]"}}]`,
+			data:      `{"labels":{"app[web]":"up"},"count*":2,"tags[0]":"up","tags":["down"]}`,
+			expected:  true,
+		},
+		{
+			name:      "path in nested and reference",
+			condition: `[{"orders[-1].items.$nested":{"sku.$eq":{"$field":"tags[0]"}}}]`,
+			data:      `{"orders":[{"items":[]},{"items":[{"sku":"x","tags":["x"]}]}]}`,
+			expected:  true,
+		},
+		{
+			name:      "jsonpath recursive descent",
+			condition: `[{"$..user.id.$eq":42}]`,
+			data:      `{"event":{"actor":{"user":{"id":42}}},"user":{"id":1}}`,
+			expected:  true,
+		},
+		{
+			name:      "jsonpath filter",
+			condition: `[{"$.items[?(@.price > 100 && @.qty >= 2)].sku.$all.sw":"gpu-"}]`,
+			data:      `{"items":[{"sku":"gpu-1","price":900,"qty":2},{"sku":"cable","price":5,"qty":10}]}`,
+			expected:  true,
+		},
+		{
+			name:      "jsonpath filter no match",
+			condition: `[{"$.items[?(@.price > 1000)].sku.$exists":true}]`,
+			data:      `{"items":[{"sku":"gpu-1","price":900}]}`,
+			expected:  false,
+		},
+		{
+			name:      "jsonpath object form",
+			condition: `[{"$path":[{"select":"$.items[?(@.note == 'a.$b')].sku","eq":"x"},{"select":"$.items[*]","count":{"gte":2}}]}]`,
+			data:      `{"items":[{"sku":"x","note":"a.$b"},{"sku":"y"}]}`,
+			expected:  true,
+		},
+		{
+			name:      "or group",
+			condition: `[{"$or":[{"a.$eq":1},{"b.$eq":2}], "c.$eq":3}]`,
+			data:      `{"b":2,"c":3}`,
+			expected:  true,
+		},
+		{
+			name:      "or group no match",
+			condition: `[{"$or":[{"a.$eq":1},{"b.$eq":2}], "c.$eq":3}]`,
+			data:      `{"a":2,"b":1,"c":3}`,
+			expected:  false,
+		},
+		{
+			name:      "and group",
+			condition: `[{"$and":[{"$or":[{"a.$eq":1},{"b.$eq":2}]},{"$or":[{"c.$eq":3},{"d.$eq":4}]}]}]`,
+			data:      `{"a":1,"d":4}`,
+			expected:  true,
+		},
+		{
+			name:      "not group",
+			condition: `[{"$not":{"a.$eq":1,"b.$eq":2}}]`,
+			data:      `{"a":1,"b":3}`,
+			expected:  true,
+		},
+		{
+			name:      "deeply nested groups",
+			condition: `[{"$or":[{"$and":[{"$or":[{"a.$eq":1},{"b.$eq":2}]},{"c.$eq":3}]},{"d.$eq":4}]}]`,
+			data:      `{"b":2,"c":3}`,
+			expected:  true,
+		},
+		{
+			name:      "groups inside nested",
+			condition: `[{"a.$nested":{"$or":[{"a.$eq":3},{"b.$eq":"x"}],"$not":{"c.$eq":true}}}]`,
+			data:      `{"a":[{"a":1,"b":"x","c":true},{"a":3,"c":false}]}`,
+			expected:  true,
+		},
 	}
 
 	for _, tt := range table {
@@ -78,6 +349,281 @@ func TestRule(t *testing.T) {
 	}
 }
 
+func TestRuleOptions(t *testing.T) {
+	table := []struct {
+		name      string
+		condition string
+		data      string
+		options   []RuleOption
+		expected  bool
+	}{
+		{
+			name:      "case sensitive by default",
+			condition: `[{"user.$eq":"Admin"}]`,
+			data:      `{"user":"ADMIN"}`,
+			expected:  false,
+		},
+		{
+			name:      "case folding",
+			condition: `[{"user.$eq":"Admin","host.$sw":"WEB","role.$in":["Root","Operator"]}]`,
+			data:      `{"user":"ADMIN","host":"web-01","role":"root"}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "case folding contains any of",
+			condition: `[{"cmd.$containsAnyOf":["MIMIKATZ","PsExec"]}]`,
+			data:      `{"cmd":"psexec.exe -s"}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "case folding keeps regex classes",
+			condition: `[{"user.$rgx":"^AD\\S+$"}]`,
+			data:      `{"user":"admin"}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "case folding regex literals",
+			condition: `[{"street.$rgx":"^straße\\s+\\d+$","city.$nrgx":"^MÜNCHEN$"}]`,
+			data:      `{"street":"STRASSE 12","city":"Berlin"}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "case folding regex classes",
+			condition: `[{"user.$rgx":"^[A-Z]+$"}]`,
+			data:      `{"user":"Admin"}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "case folding leaves other operators",
+			condition: `[{"user.$isType":"string","count.$gt":1}]`,
+			data:      `{"user":"ADMIN","count":2}`,
+			options:   []RuleOption{WithCaseFolding()},
+			expected:  true,
+		},
+		{
+			name:      "unicode normalization",
+			condition: `[{"city.$eq":"Zu\u0308rich"}]`,
+			data:      `{"city":"Z\u00fcrich"}`,
+			options:   []RuleOption{WithUnicodeNormalization()},
+			expected:  true,
+		},
+		{
+			name:      "unicode normalization stays case sensitive",
+			condition: `[{"city.$eq":"ZU\u0308RICH"}]`,
+			data:      `{"city":"Z\u00fcrich"}`,
+			options:   []RuleOption{WithUnicodeNormalization()},
+			expected:  false,
+		},
+		{
+			name:      "clock",
+			condition: `[{"seen.$withinLast":"15m","logins.$all.withinLast":"1h"}]`,
+			data:      `{"seen":"2024-09-15T11:50:00Z","logins":["2024-09-15T11:10:00Z","2024-09-15T11:55:00Z"]}`,
+			options:   []RuleOption{WithClock(func() time.Time { return time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC) })},
+			expected:  true,
+		},
+		{
+			name:      "clock too late",
+			condition: `[{"seen.$withinLast":"15m"}]`,
+			data:      `{"seen":"2024-09-15T11:50:00Z"}`,
+			options:   []RuleOption{WithClock(func() time.Time { return time.Date(2024, 9, 15, 13, 0, 0, 0, time.UTC) })},
+			expected:  false,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			r, err := NewRule(strings.NewReader(tt.condition), map[string]interface{}{}, &LogEventHandler{}, tt.options...)
+			if err != nil {
+				t.Fatal("unable to parse to rule, received error : ", err)
+			}
+			if got := r.IsMatch(data); got != tt.expected {
+				t.Errorf("IsMatch() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuleParams(t *testing.T) {
+	table := []struct {
+		name      string
+		condition string
+		params    map[string]interface{}
+		data      string
+		expected  bool
+	}{
+		{
+			name:      "list parameter",
+			condition: `[{"user.$in":"${params.watchlist}"}]`,
+			params:    map[string]interface{}{"watchlist": []string{"alice", "bob"}},
+			data:      `{"user":"bob"}`,
+			expected:  true,
+		},
+		{
+			name:      "number parameter",
+			condition: `[{"attempts.$gte":"${params.threshold}"}]`,
+			params:    map[string]interface{}{"threshold": 5},
+			data:      `{"attempts":4}`,
+			expected:  false,
+		},
+		{
+			name:      "nested parameter",
+			condition: `[{"src.$ipInRange":"${params.tenant.network}"}]`,
+			params:    map[string]interface{}{"tenant": map[string]string{"network": "10.1.0.0/16"}},
+			data:      `{"src":"10.1.2.3"}`,
+			expected:  true,
+		},
+		{
+			name:      "parameter embedded in string",
+			condition: `[{"host.$eq":"${params.tenant}-web-${params.index}"}]`,
+			params:    map[string]interface{}{"tenant": "acme", "index": 1},
+			data:      `{"host":"acme-web-1"}`,
+			expected:  true,
+		},
+		{
+			name:      "parameter inside array and nested",
+			condition: `[{"items.$nested":{"price.$between":[0,"${params.max}"]}}]`,
+			params:    map[string]interface{}{"max": 100},
+			data:      `{"items":[{"price":150},{"price":50}]}`,
+			expected:  true,
+		},
+		{
+			name:      "parameter as field reference",
+			condition: `[{"a.$eq":{"$field":"${params.field}"}}]`,
+			params:    map[string]interface{}{"field": "b"},
+			data:      `{"a":1,"b":1}`,
+			expected:  true,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal("Invalid data", err)
+			}
+			r, err := NewRule(strings.NewReader(tt.condition), tt.params, &LogEventHandler{})
+			if err != nil {
+				t.Fatal("unable to parse to rule, received error : ", err)
+			}
+			if got := r.IsMatch(data); got != tt.expected {
+				t.Errorf("IsMatch() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuleJsonNumber(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`{"a":{"id":9007199254740993},"b":500}`))
+	decoder.UseNumber()
+	data := make(map[string]interface{})
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal("Invalid data", err)
+	}
+	condition := `[{"b.$gte":500,"a.id.$eq":"${params.id}"}]`
+
+	r, err := NewRule(strings.NewReader(condition), map[string]interface{}{"id": int64(9007199254740993)}, &LogEventHandler{})
+	if err != nil {
+		t.Fatal("unable to parse to rule, received error : ", err)
+	}
+	if !r.IsMatch(data) {
+		t.Errorf("IsMatch() = false, want true")
+	}
+	r, err = NewRule(strings.NewReader(condition), map[string]interface{}{"id": int64(9007199254740992)}, &LogEventHandler{})
+	if err != nil {
+		t.Fatal("unable to parse to rule, received error : ", err)
+	}
+	if r.IsMatch(data) {
+		t.Errorf("IsMatch() = true, want false")
+	}
+}
+
+func TestRuleJsonNumberLiteral(t *testing.T) {
+	handlerMock := &eventHandlerMock{}
+	handlerMock.On("Handle").Times(1)
+	r, err := NewRule(strings.NewReader(`[{"id.$eq":9007199254740993}]`), map[string]interface{}{}, handlerMock)
+	if err != nil {
+		t.Fatal("unable to parse to rule, received error : ", err)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(`{"id":9007199254740993}`))
+	decoder.UseNumber()
+	data := make(map[string]interface{})
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal("Invalid data", err)
+	}
+	if !r.IsMatch(data) {
+		t.Errorf("IsMatch() = false, want true")
+	}
+
+	for _, event := range []string{`{"id":9007199254740992}`, `{"id":9007199254740993}`, `{"id":9007199254740993.5}`} {
+		if err := r.Send(strings.NewReader(event)); err != nil {
+			t.Fatal("unable to send event, received error", err)
+		}
+	}
+	handlerMock.AssertExpectations(t)
+}
+
+func TestRuleParsingErrors(t *testing.T) {
+	table := []struct {
+		name      string
+		condition string
+	}{
+		{name: "missing operator", condition: `[{"a":1}]`},
+		{name: "unknown operator", condition: `[{"a.$equals":1}]`},
+		{name: "unknown quantified operator", condition: `[{"a.$all.equals":1}]`},
+		{name: "count without comparison", condition: `[{"a.$count":{"where":{"b.$eq":1}}}]`},
+		{name: "count with two comparisons", condition: `[{"a.$count":{"gt":1,"lt":5}}]`},
+		{name: "count with unknown comparison", condition: `[{"a.$count":{"sw":"1"}}]`},
+		{name: "count with invalid comparison value", condition: `[{"a.$count":{"eq":"1"}}]`},
+		{name: "invalid path", condition: `[{"a[x].$eq":1}]`},
+		{name: "invalid reference path", condition: `[{"a.$eq":{"$field":"b[0"}}]`},
+		{name: "invalid jsonpath", condition: `[{"$.items[?(@.price >)].$exists":true}]`},
+		{name: "path without select", condition: `[{"$path":{"eq":1}}]`},
+		{name: "path with two operators", condition: `[{"$path":{"select":"$.a","gt":1,"lt":5}}]`},
+		{name: "path select is not a jsonpath", condition: `[{"$path":{"select":"a.b","eq":1}}]`},
+		{name: "every value is not a map", condition: `[{"a.$every":[{"b.$eq":1}]}]`},
+		{name: "exists value is not a bool", condition: `[{"a.$exists":"yes"}]`},
+		{name: "unknown type", condition: `[{"a.$isType":"date"}]`},
+		{name: "in value is not an array", condition: `[{"a.$in":"a"}]`},
+		{name: "contains value is not a string", condition: `[{"a.$contains":1}]`},
+		{name: "contains any of has a number", condition: `[{"a.$containsAnyOf":["a",1]}]`},
+		{name: "invalid timestamp", condition: `[{"ts.$before":"yesterday"}]`},
+		{name: "invalid duration", condition: `[{"ts.$withinLast":"15 minutes"}]`},
+		{name: "invalid day of week", condition: `[{"ts.$dayOfWeek":["someday"]}]`},
+		{name: "invalid timezone", condition: `[{"ts.$hourBetween":{"from":9,"to":17,"timezone":"Mars/Olympus"}}]`},
+		{name: "invalid hour", condition: `[{"ts.$hourBetween":[9,25]}]`},
+		{name: "invalid CIDR", condition: `[{"ip.$ipInRange":"10.0.0.0/33"}]`},
+		{name: "invalid IP in list", condition: `[{"ip.$ipInAnyRange":["10.0.0.0/8","10.0.0.256"]}]`},
+		{name: "invalid ip version", condition: `[{"ip.$ipVersion":5}]`},
+		{name: "undefined parameter", condition: `[{"a.$in":"${params.watchlist}"}]`},
+		{name: "undefined embedded parameter", condition: `[{"a.$eq":"x-${params.suffix}"}]`},
+		{name: "parameter of invalid type", condition: `[{"a.$gt":"${params.rule_id}"}]`},
+		{name: "gte value is not a number", condition: `[{"a.$gte":"1"}]`},
+		{name: "between bounds are reversed", condition: `[{"a.$between":[599,500]}]`},
+		{name: "between has one bound", condition: `[{"a.$between":[500]}]`},
+		{name: "in value has an object", condition: `[{"a.$in":[{"a":1}]}]`},
+		{name: "or group is not a list", condition: `[{"$or":{"a.$eq":1}}]`},
+		{name: "and group element is not a map", condition: `[{"$and":[1]}]`},
+		{name: "not group is not a map", condition: `[{"$not":[{"a.$eq":1}]}]`},
+	}
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRule(strings.NewReader(tt.condition), map[string]interface{}{"rule_id": "r1"}, &LogEventHandler{}); err == nil {
+				t.Errorf("NewRule() error = nil, want parsing error")
+			}
+		})
+	}
+}
+
 func TestRuleEventHandlerChaining(t *testing.T) {
 	table := []struct {
 		name              string
@@ -97,15 +643,15 @@ func TestRuleEventHandlerChaining(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			handlerMock := &eventHandlerMock{}
-			RegisterEventHandlerParser("MockEventHandler", func(params map[string]interface{}) (eventHandler, error) {
+			RegisterEventHandlerParser("MockEventHandler", func(params map[string]interface{}) (EventHandler, error) {
 				return handlerMock, nil
 			})
-			handlerMock.On("call").Times(1)
-			eventHandler, err := GetEventHandlerChain(strings.NewReader(tt.eventHandlerChain))
+			handlerMock.On("Handle").Times(1)
+			EventHandler, err := GetEventHandlerChain(strings.NewReader(tt.eventHandlerChain))
 			if err != nil {
 				t.Fatal("unable to parse event handler chain", err)
 			}
-			r, err := NewRule(strings.NewReader(tt.condition), map[string]interface{}{}, eventHandler)
+			r, err := NewRule(strings.NewReader(tt.condition), map[string]interface{}{}, EventHandler)
 			if err != nil {
 				t.Fatal("unable to parse to rule, received error : ", err)
 			}
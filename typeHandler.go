@@ -2,23 +2,238 @@ package jsontology
 
 import (
 	"fmt"
-	"net"
-	"reflect"
+	"net/netip"
 	"regexp"
+	"strings"
+	"time"
 )
 
 func isNumber(value interface{}) (interface{}, error) {
-	v1 := reflect.ValueOf(value)
-
-	switch v1.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32,
-		reflect.Float64:
+	if _, ok := asNumberValue(value); ok {
 		return value, nil
 	}
 	return nil, fmt.Errorf("validation failed, %v is not a number", value)
 }
 
+// numberRange is the parsed value of the between operator.
+type numberRange struct {
+	low       interface{}
+	high      interface{}
+	exclusive bool
+}
+
+func asNumberRange(value interface{}) (interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok || len(values) < 2 || len(values) > 3 {
+		return nil, fmt.Errorf("validation failed, %v is not an array of [low, high] or [low, high, exclusive]", value)
+	}
+	low, err := isNumber(values[0])
+	if err != nil {
+		return nil, err
+	}
+	high, err := isNumber(values[1])
+	if err != nil {
+		return nil, err
+	}
+	if isLessThan(low, high) {
+		return nil, fmt.Errorf("validation failed, lower bound %v is greater than upper bound %v", low, high)
+	}
+	r := numberRange{low: low, high: high}
+	if len(values) == 3 {
+		if r.exclusive, ok = values[2].(bool); !ok {
+			return nil, fmt.Errorf("validation failed, exclusive flag %v is not a bool", values[2])
+		}
+	}
+	return r, nil
+}
+
+// weekdays is the parsed value of the dayOfWeek operator.
+type weekdays struct {
+	days     [7]bool
+	location *time.Location
+}
+
+// hourRange is the parsed value of the hourBetween operator.
+type hourRange struct {
+	from     int
+	to       int
+	location *time.Location
+}
+
+func asTime(value interface{}) (interface{}, error) {
+	t, ok := asEventTime(value)
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is neither an RFC3339 timestamp nor an epoch time", value)
+	}
+	return t, nil
+}
+
+func asDuration(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not a string", value)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed, %w", err)
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("validation failed, duration %s is negative", str)
+	}
+	return d, nil
+}
+
+// asLocation reads the optional "timezone" entry of a time operator's value, defaulting to UTC.
+func asLocation(value map[string]interface{}) (*time.Location, error) {
+	name, ok := value["timezone"]
+	if !ok {
+		return time.UTC, nil
+	}
+	nameStr, ok := name.(string)
+	if !ok {
+		return nil, fmt.Errorf("validation failed, timezone %v is not a string", name)
+	}
+	location, err := time.LoadLocation(nameStr)
+	if err != nil {
+		return nil, fmt.Errorf("validation failed, %w", err)
+	}
+	return location, nil
+}
+
+func asWeekdays(value interface{}) (interface{}, error) {
+	w := weekdays{location: time.UTC}
+	days, ok := value.([]interface{})
+	if objectValue, isObject := value.(map[string]interface{}); isObject {
+		var err error
+		if w.location, err = asLocation(objectValue); err != nil {
+			return nil, err
+		}
+		days, ok = objectValue["days"].([]interface{})
+	}
+	if !ok || len(days) == 0 {
+		return nil, fmt.Errorf("validation failed, %v is neither a list of days nor an object with days and timezone", value)
+	}
+	for _, eachDay := range days {
+		dayStr, _ := eachDay.(string)
+		day, ok := weekdayNames[strings.ToLower(dayStr)]
+		if !ok {
+			return nil, fmt.Errorf("validation failed, %v is not a day of the week", eachDay)
+		}
+		w.days[day] = true
+	}
+	return w, nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func asHourRange(value interface{}) (interface{}, error) {
+	r := hourRange{location: time.UTC}
+	var from, to interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) != 2 {
+			return nil, fmt.Errorf("validation failed, %v is not an array of [from, to]", value)
+		}
+		from, to = v[0], v[1]
+	case map[string]interface{}:
+		var err error
+		if r.location, err = asLocation(v); err != nil {
+			return nil, err
+		}
+		from, to = v["from"], v["to"]
+	default:
+		return nil, fmt.Errorf("validation failed, %v is neither an array of [from, to] nor an object with from, to and timezone", value)
+	}
+
+	var ok bool
+	if r.from, ok = asHour(from, 23); !ok {
+		return nil, fmt.Errorf("validation failed, %v is not an hour between 0 and 23", from)
+	}
+	if r.to, ok = asHour(to, 24); !ok {
+		return nil, fmt.Errorf("validation failed, %v is not an hour between 0 and 24", to)
+	}
+	return r, nil
+}
+
+func asHour(value interface{}, max int64) (int, bool) {
+	n, ok := asNumberValue(value)
+	if !ok || n.float() != float64(int64(n.float())) || n.float() < 0 || int64(n.float()) > max {
+		return 0, false
+	}
+	return int(n.float()), true
+}
+
+func isBool(value interface{}) (interface{}, error) {
+	if _, ok := value.(bool); !ok {
+		return nil, fmt.Errorf("validation failed, %v is not a bool", value)
+	}
+	return value, nil
+}
+
+func asJsonType(value interface{}) (interface{}, error) {
+	typeStr, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not a string", value)
+	}
+	switch typeStr {
+	case stringType, numberType, boolType, arrayType, objectType:
+		return typeStr, nil
+	}
+	return nil, fmt.Errorf("validation failed, %s is not a known type", typeStr)
+}
+
+func asValueSet(value interface{}) (interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not an array", value)
+	}
+	set := make(valueSet, len(values))
+	for _, eachValue := range values {
+		key, ok := setKey(eachValue)
+		if !ok {
+			return nil, fmt.Errorf("validation failed, %v is not a string, number, bool or null", eachValue)
+		}
+		set[key] = struct{}{}
+	}
+	return set, nil
+}
+
+func isString(value interface{}) (interface{}, error) {
+	if _, ok := value.(string); !ok {
+		return nil, fmt.Errorf("validation failed, %v is not a string", value)
+	}
+	return value, nil
+}
+
+func asKeywordMatcher(value interface{}) (interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not an array", value)
+	}
+	keywords := make([]string, 0, len(values))
+	for _, eachValue := range values {
+		keyword, ok := eachValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("validation failed, %v is not a string", eachValue)
+		}
+		keywords = append(keywords, keyword)
+	}
+	return newAhoCorasick(keywords), nil
+}
+
+func asFoldedString(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not a string", value)
+	}
+	return foldString(str), nil
+}
+
 func asRegexExpression(value interface{}) (interface{}, error) {
 
 	regStr, ok := value.(string)
@@ -35,20 +250,53 @@ func asRegexExpression(value interface{}) (interface{}, error) {
 func asIpNet(value interface{}) (interface{}, error) {
 	str, ok := value.(string)
 	if !ok {
-		return false, fmt.Errorf("validation failed, %v is not a string", value)
+		return nil, fmt.Errorf("validation failed, %v is not a string", value)
 	}
+	return parsePrefix(str)
+}
 
-	ipv4Regex := regexp.MustCompile(`^((25[0-5]|2[0-4]\d|1\d{2}|[1-9]\d|[1-9])\.){3}(25[0-5]|2[0-4]\d|1\d{2}|[1-9]\d|[1-9])$`)
-	ipv6Regex := regexp.MustCompile(`^([0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}$`)
-	
-	if !(ipv4Regex.MatchString(str) || ipv6Regex.MatchString(str)) {
-		return false, fmt.Errorf("validation failed, invalid IP address: %s", str)
+func asPrefixTrie(value interface{}) (interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("validation failed, %v is not an array", value)
 	}
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, eachValue := range values {
+		str, ok := eachValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("validation failed, %v is not a string", eachValue)
+		}
+		prefix, err := parsePrefix(str)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return newPrefixTrie(prefixes), nil
+}
 
-	// If networkStr is an IP with a subnet (CIDR notation)
-	_, subnetStr, err := net.ParseCIDR(str)
-	if err != nil {
-		return false, fmt.Errorf("validation failed, invalid CIDR subnet: %s", str)
+func asIPVersion(value interface{}) (interface{}, error) {
+	if n, ok := asNumberValue(value); ok && (n.float() == 4 || n.float() == 6) {
+		return int(n.float()), nil
+	}
+	return nil, fmt.Errorf("validation failed, %v is neither 4 nor 6", value)
+}
+
+// parsePrefix parses an IPv4 or IPv6 CIDR, or a single IP address which is treated as a prefix containing only itself.
+func parsePrefix(str string) (netip.Prefix, error) {
+	if strings.Contains(str, "/") {
+		prefix, err := netip.ParsePrefix(str)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("validation failed, invalid CIDR subnet: %s", str)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, ok := parseAddr(str)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("validation failed, invalid IP address: %s", str)
 	}
-	return subnetStr, nil 
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
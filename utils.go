@@ -1,107 +1,158 @@
 package jsontology
 
-import "strings"
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
 
-func allMatch(slice []bool) bool {
-	for _, v := range slice {
-		if !v {
-			return false
-		}
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// decodeJSONObject reads all of data and decodes it as a JSON object, see decodeJSON.
+func decodeJSONObject(data io.Reader) (map[string]interface{}, error) {
+	var parsedData map[string]interface{}
+	if err := decodeJSON(data, &parsedData); err != nil {
+		return nil, err
 	}
-	return true
+	return parsedData, nil
 }
 
-func anyMatch(slice []bool) bool {
-	for _, v := range slice {
-		if v {
-			return true
-		}
+// decodeJSON decodes data like json.Unmarshal, but keeps numbers as json.Number so that integers
+// beyond the precision of float64 keep their exact value.
+func decodeJSON(data io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(data)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
 	}
-	return false
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("parsing error, unexpected data after JSON value")
+	}
+	return nil
 }
 
-// concatMaps merges two maps into a new map. If a key exists in both maps,
-// the value from the second map is appended to the value in the first map.
-// If the value in the first map is not a slice, it is converted to a slice
-// before appending the value from the second map.
-//
-// Parameters:
-// - m1: The first map to merge.
-// - m2: The second map to merge.
-//
-// Returns:
-// - A new map containing the merged key-value pairs.
-func concatMaps(m1 map[string]interface{}, m2 map[string]interface{}) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	for k, v := range m1 {
-		result[k] = v
-	}
-
-	for k, v := range m2 {
-
-		if value, ok := result[k]; ok {
-
-			switch vv := value.(type) {
-			case []interface{}:
-				result[k] = append(vv, v)
-			default:
-				result[k] = []interface{}{value, v}
-			}
-
-		} else {
-			result[k] = v
-		}
+// valueSet is a set of scalar values allowing constant time membership lookups.
+type valueSet map[interface{}]struct{}
+
+// setKey returns the key under which value is stored in a valueSet.
+// Numbers of any kind are stored under a common key so that equal numbers match regardless of their kind.
+// Returns false if value cannot be stored in a valueSet.
+func setKey(value interface{}) (interface{}, bool) {
+	if n, ok := asNumberValue(value); ok {
+		return n.key(), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.String:
+		return v.String(), true
+	}
+	return nil, false
+}
+
+func (s valueSet) contains(value interface{}) bool {
+	key, ok := setKey(value)
+	if !ok {
+		return false
+	}
+	_, ok = s[key]
+	return ok
+}
+
+// asArray returns value itself if it is an array, otherwise a single element array holding value.
+func asArray(value interface{}) []interface{} {
+	if array, ok := value.([]interface{}); ok {
+		return array
+	}
+	return []interface{}{value}
+}
+
+// nfcString returns s in Unicode NFC form.
+func nfcString(s string) string {
+	if isASCII(s) {
+		return s
 	}
-	return result
+	return norm.NFC.String(s)
 }
 
-// transformJSON is a recursive function that transforms a nested JSON-like data structure into a flat map.
-// It handles bool, int, float64, string, map[string]interface{}, and []interface{} types.
-//
-// Parameters:
-// - data: The input data to be transformed. It can be of any type mentioned above.
-// - currentPath: The current path of the data in the nested structure. It is used to construct the keys in the flat map.
-//
-// Returns:
-// - A map[string]interface{} representing the flat map of the transformed data.
-//
-// Note:
-// - If the input data is a map[string]interface{}, it recursively calls itself for each value with the updated currentPath.
-// - If the input data is a []interface{}, it iterates over each value and handles it accordingly.
-// - If the input data is a bool, int, float64, or string, it checks if the currentPath contains a dot and if it does, it adds the currentPath and the value to the result map.
-func transformJSON(data interface{}, currentPath string) map[string]interface{} {
-	var result = make(map[string]interface{})
-	switch v := data.(type) {
-	case bool, int, float64, string:
-		if strings.Contains(currentPath, ".") {
-			result[currentPath] = v
+// foldString returns s with Unicode case folding applied and in NFC form, so that strings
+// differing only in case or in their normalization form are returned equal.
+func foldString(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	// a Caser is stateful and can not be shared between goroutines
+	return norm.NFC.String(cases.Fold().String(norm.NFC.String(s)))
+}
+
+// foldRegex returns the regular expression expr matching case-insensitive, with case folding applied to its literals
+// so that they match strings folded by foldString, e.g. "straße" matching the folded "strasse". Characters within
+// classes are only matched case-insensitive. An invalid expression is returned as is, to be reported when compiled.
+func foldRegex(expr string) string {
+	re, err := syntax.Parse(expr, syntax.Perl|syntax.FoldCase)
+	if err != nil {
+		return expr
+	}
+	foldLiterals(re)
+	return re.String()
+}
+
+func foldLiterals(re *syntax.Regexp) {
+	if re.Op == syntax.OpLiteral {
+		re.Rune = []rune(foldString(string(re.Rune)))
+	}
+	for _, sub := range re.Sub {
+		foldLiterals(sub)
+	}
+}
+
+// normalizeWithOffsets returns s normalized by normalizeString, one character at a time, along with the boundaries
+// between its characters in the normalized string and the matching boundaries in s.
+func normalizeWithOffsets(s string, normalizeString func(string) string) (string, []int, []int) {
+	var normalized strings.Builder
+	normalizedBounds, bounds := []int{0}, []int{0}
+	for i := 0; i < len(s); {
+		end := i + norm.NFC.NextBoundaryInString(s[i:], true)
+		if end <= i {
+			end = len(s)
 		}
-	case map[string]interface{}:
-		for key, value := range v {
-			if currentPath != "" {
-				key = currentPath + "." + key
-			}
-			result = concatMaps(result, transformJSON(value, key))
+		normalized.WriteString(normalizeString(s[i:end]))
+		normalizedBounds, bounds = append(normalizedBounds, normalized.Len()), append(bounds, end)
+		i = end
+	}
+	return normalized.String(), normalizedBounds, bounds
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
 		}
+	}
+	return true
+}
+
+// normalizeStrings applies normalizeString to value if it is a string, or to each string element if it is an array.
+func normalizeStrings(value interface{}, normalizeString func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return normalizeString(v)
 	case []interface{}:
-		for _, eachValue := range v {
-			switch eachValue.(type) {
-			case map[string]interface{}:
-				result = concatMaps(result, transformJSON(eachValue, currentPath))
-			default:
-				if _, ok := result[currentPath]; ok {
-					d := result[currentPath].([]interface{})
-					result[currentPath] = append(d, eachValue)
-				} else {
-					if strings.Contains(currentPath, ".") {
-						result[currentPath] = []interface{}{eachValue}
-					}
-				}
-			}
+		normalized := make([]interface{}, len(v))
+		for i, eachValue := range v {
+			normalized[i] = normalizeStrings(eachValue, normalizeString)
 		}
+		return normalized
 	}
-	return result
+	return value
 }
 
 func filter[T any](iterable []T, function func(T) bool) (ret []T) {